@@ -0,0 +1,130 @@
+package asm
+
+import (
+	"strings"
+)
+
+// indentation is the indentation for instructions in formatted source.
+const indentation = "    "
+
+// FormatSource formats Hack assembly source in the canonical style:
+//
+//   - Labels and directives start at the beginning of the line; instructions, macro calls, and
+//     pseudo-instructions are indented by four spaces.
+//   - Dest fields are written in the conventional order (MD, AM, AD, AMD), and C-instructions
+//     don't contain whitespace.
+//   - Comments start with "// ". Full-line comments are indented like the line after them, and
+//     trailing comments on consecutive lines are aligned.
+//   - Runs of blank lines are reduced to one, and blank lines at the start and end are removed.
+//
+// Lines that can't be parsed are only re-indented, so FormatSource works on programs with errors.
+func FormatSource(src []byte) []byte {
+	type line struct {
+		indent, code, comment string
+	}
+	var lines []line
+	for _, text := range strings.Split(string(src), "\n") {
+		code, comment, hasComment := strings.Cut(strings.TrimRight(text, "\r"), "//")
+		l := line{code: formatCode(strings.TrimSpace(code))}
+		if hasComment {
+			l.comment = formatComment(comment)
+		}
+		if l.code != "" && !startsFlush(l.code) {
+			l.indent = indentation
+		}
+		// remove redundant blank lines
+		blank := l.code == "" && l.comment == ""
+		if blank && (len(lines) == 0 || lines[len(lines)-1] == line{}) {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == (line{}) {
+		lines = lines[:len(lines)-1]
+	}
+
+	// full-line comments are indented like the next line of code
+	indent := ""
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := &lines[i]
+		switch {
+		case l.code != "":
+			indent = l.indent
+		case l.comment != "":
+			l.indent = indent
+		}
+	}
+
+	var b strings.Builder
+	for start := 0; start < len(lines); {
+		// find a run of lines with trailing comments and align the comments
+		end := start + 1
+		if lines[start].code != "" && lines[start].comment != "" {
+			for end < len(lines) && lines[end].code != "" && lines[end].comment != "" {
+				end++
+			}
+		}
+		width := 0
+		for _, l := range lines[start:end] {
+			width = max(width, len(l.indent)+len(l.code))
+		}
+		for _, l := range lines[start:end] {
+			b.WriteString(l.indent)
+			b.WriteString(l.code)
+			if l.comment != "" {
+				if l.code != "" {
+					b.WriteString(strings.Repeat(" ", width-len(l.indent)-len(l.code)+1))
+				}
+				b.WriteString(l.comment)
+			}
+			b.WriteString("\n")
+		}
+		start = end
+	}
+	return []byte(b.String())
+}
+
+// startsFlush reports whether a line of formatted code starts at the beginning of the line rather
+// than being indented: labels and directives.
+func startsFlush(code string) bool {
+	return strings.HasPrefix(code, "(") || strings.HasPrefix(code, ".")
+}
+
+// formatCode formats the code on a line, without the comment and surrounding whitespace. Lines
+// the lexer can't handle, like macro definitions with commas, are left alone.
+func formatCode(code string) string {
+	text, _, err := normalize(code)
+	if err != nil {
+		return code
+	}
+	if !strings.ContainsAny(text, "=;") || strings.HasPrefix(text, ".") {
+		return text
+	}
+	c, _ := parseCInstruction(text)
+	c.Dest = canonicalDest(c.Dest)
+	return c.String()
+}
+
+// formatComment formats a comment, given the text after "//".
+func formatComment(comment string) string {
+	comment = strings.TrimRight(comment, " \t")
+	if comment != "" && !strings.HasPrefix(comment, " ") && !strings.HasPrefix(comment, "/") {
+		comment = " " + comment
+	}
+	return "//" + comment
+}
+
+// canonicalDest returns a dest field in the order used in the book, for example "MD" for "DM".
+// Dest fields with letters other than A, M, and D, or with a letter repeated, are returned as is.
+func canonicalDest(dest string) string {
+	var b strings.Builder
+	for _, r := range "AMD" {
+		if strings.ContainsRune(dest, r) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() != len(dest) {
+		return dest
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package asm
+
+import "testing"
+
+func TestFormatSource(t *testing.T) {
+	source := "\n\n//Computes max\n" +
+		"  @R10 //first\n" +
+		"D=M     // x\n" +
+		"\t@R1\n" +
+		"DM = D - M ; JGT\n" +
+		"\n\n\n" +
+		"  // loop forever\n" +
+		"( END )\n" +
+		".equ N 3\n" +
+		"\tJMP END // pseudo-instruction\n" +
+		"ADM=0\n" +
+		"\n\n"
+	want := `    // Computes max
+    @R10 // first
+    D=M  // x
+    @R1
+    MD=D-M;JGT
+
+// loop forever
+(END)
+.equ N 3
+    JMP END // pseudo-instruction
+    AMD=0
+`
+	got := string(FormatSource([]byte(source)))
+	if got != want {
+		t.Errorf("FormatSource returned:\n%s\nwant:\n%s", got, want)
+	}
+	if again := string(FormatSource([]byte(got))); again != got {
+		t.Errorf("FormatSource isn't idempotent; formatting its output returned:\n%s", again)
+	}
+}
+
+func TestCanonicalDest(t *testing.T) {
+	cases := map[string]string{
+		"":    "",
+		"M":   "M",
+		"DM":  "MD",
+		"MA":  "AM",
+		"DA":  "AD",
+		"DMA": "AMD",
+		"MM":  "MM",
+		"MX":  "MX",
+	}
+	for dest, want := range cases {
+		if got := canonicalDest(dest); got != want {
+			t.Errorf("canonicalDest(%q) returned %q, want %q", dest, got, want)
+		}
+	}
+}
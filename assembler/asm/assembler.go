@@ -0,0 +1,343 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Options holds optional settings for Run.
+type Options struct {
+	// If Listing is not nil, Run writes a listing to it: each source line with the ROM address
+	// and binary code of its instruction, followed by the symbol table.
+	Listing io.Writer
+
+	// Format is the output format; the default is the text format used by the course tools.
+	Format Format
+
+	// VariableLimit is the highest RAM address for variables; the assembler warns about variables
+	// allocated past it. If it's zero, the assembler uses DefaultVariableLimit.
+	VariableLimit uint
+
+	// If Warnings is not nil, Run writes warnings to it, for example about labels that are never
+	// referenced.
+	Warnings io.Writer
+
+	// If Strict is set, warnings are treated as errors.
+	Strict bool
+
+	// Optimize is the optimization level, one of OptimizeNone, OptimizeLocal,
+	// OptimizeUnreachable, and OptimizeSize.
+	Optimize int
+
+	// If Savings is not nil and Optimize is set, Run writes a report to it that says how many
+	// instructions each optimization rule saved, and how many cycles outlining added.
+	Savings io.Writer
+
+	// If Object is set, Run writes a relocatable object file instead of a binary program; see
+	// Object. Format, Listing, and the checks for the memory layout don't apply to object files.
+	Object bool
+
+	// Open is used to open included files and, for RunFiles, the input files. If it's nil, the
+	// assembler uses os.Open.
+	Open func(name string) (io.ReadCloser, error)
+}
+
+func (o Options) variableLimit() uint {
+	if o.VariableLimit == 0 {
+		return DefaultVariableLimit
+	}
+	return o.VariableLimit
+}
+
+func (o Options) open(name string) (io.ReadCloser, error) {
+	if o.Open != nil {
+		return o.Open(name)
+	}
+	return os.Open(name)
+}
+
+// Run runs the assembler. It reads and parses Hack assembly from r, translates it to Hack binary
+// code, and writes the result to w. Filename is used in error messages and to find included
+// files.
+//
+// Errors in the program are returned as an ErrorList that includes all errors found, not just the
+// first one.
+func Run(filename string, r io.Reader, w io.Writer, options Options) error {
+	lines, err := readLines(filename, r)
+	if err != nil {
+		return err
+	}
+	pp := newPreprocessor(options.open)
+	lines = pp.file(filename, lines)
+	return assemble(lines, pp.errs, w, options)
+}
+
+// RunFiles is like Run, but it reads several files and combines them into one program, as if
+// they were concatenated. Labels declared in one file can be used in the others.
+func RunFiles(filenames []string, w io.Writer, options Options) error {
+	pp := newPreprocessor(options.open)
+	var lines []sourceLine
+	for _, filename := range filenames {
+		fileLines, err := readFile(options.open, filename)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, pp.file(filename, fileLines)...)
+	}
+	return assemble(lines, pp.errs, w, options)
+}
+
+// assemble translates preprocessed lines to binary code. Errs holds the errors found by the
+// preprocessor.
+func assemble(lines []sourceLine, errs ErrorList, w io.Writer, options Options) error {
+	// The program is parsed once into a list of statements; the assembler then makes two passes
+	// over that list.
+	statements, parseErrs := parse(lines)
+	errs = append(errs, parseErrs...)
+
+	// The optimizer works on the parsed program, before addresses are assigned.
+	var savings []saving
+	if options.Optimize > OptimizeNone {
+		statements, savings = optimize(statements, options.Optimize)
+	}
+
+	// The first pass creates a symbol table.
+	symbolTable, err := createSymbolTable(statements)
+	var symbolErrs ErrorList
+	if !errors.As(err, &symbolErrs) && err != nil {
+		return err
+	}
+	errs = append(errs, symbolErrs...)
+
+	if options.Object {
+		return assembleObject(lines, statements, symbolTable, errs, w)
+	}
+
+	// The second pass translate assembly to binary code. Even if there were errors so far, we
+	// still run the second pass to find any remaining errors.
+	var l *listing
+	if options.Listing != nil {
+		l = newListing()
+	}
+	program, err := translate(statements, symbolTable, l)
+	var translateErrs ErrorList
+	if !errors.As(err, &translateErrs) && err != nil {
+		return err
+	}
+
+	errs = append(errs, translateErrs...)
+
+	// Finally, look for problems with the memory layout and symbols that are probably mistakes.
+	if len(errs) == 0 {
+		var warnings ErrorList
+		for _, e := range diagnose(statements, symbolTable, options.variableLimit()) {
+			if !e.Warning {
+				errs = append(errs, e)
+			} else if options.Strict {
+				e.Warning = false
+				errs = append(errs, e)
+			} else {
+				warnings = append(warnings, e)
+			}
+		}
+		if options.Warnings != nil && len(warnings) > 0 {
+			warnings.Sort()
+			if _, err := fmt.Fprintln(options.Warnings, warnings); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		errs.Sort()
+		return errs
+	}
+
+	err = writeProgram(w, program, options.Format)
+	if err != nil {
+		return err
+	}
+	if l != nil {
+		err = l.write(options.Listing, lines, symbolTable)
+		if err != nil {
+			return err
+		}
+	}
+	if options.Savings != nil && savings != nil {
+		return writeSavings(options.Savings, savings)
+	}
+	return nil
+}
+
+// assembleObject runs the second pass for an object file and writes it to w.
+func assembleObject(lines []sourceLine, statements []Statement, symbolTable map[string]uint, errs ErrorList, w io.Writer) error {
+	object, err := translateObject(statements, symbolTable)
+	var translateErrs ErrorList
+	if !errors.As(err, &translateErrs) && err != nil {
+		return err
+	}
+	errs = append(errs, translateErrs...)
+	if len(errs) > 0 {
+		errs.Sort()
+		return errs
+	}
+	if len(lines) > 0 {
+		object.Source = lines[0].pos.File
+	}
+	return WriteObject(w, object)
+}
+
+func predefinedSymbols() map[string]uint {
+	return map[string]uint{
+		"R0":     0,
+		"R1":     1,
+		"R2":     2,
+		"R3":     3,
+		"R4":     4,
+		"R5":     5,
+		"R6":     6,
+		"R7":     7,
+		"R8":     8,
+		"R9":     9,
+		"R10":    10,
+		"R11":    11,
+		"R12":    12,
+		"R13":    13,
+		"R14":    14,
+		"R15":    15,
+		"SP":     0,
+		"LCL":    1,
+		"ARG":    2,
+		"THIS":   3,
+		"THAT":   4,
+		"SCREEN": 16384,
+		"KBD":    24576,
+	}
+}
+
+func createSymbolTable(statements []Statement) (map[string]uint, error) {
+	symbolTable := predefinedSymbols()
+	predefined := predefinedSymbols()
+	var address uint
+	var errs ErrorList
+	declarations := make(map[string]*Error)
+
+	// define adds a label or constant to the symbol table, unless it's already defined
+	define := func(s *Statement, kind, symbol string, offset int, value uint) {
+		if _, ok := predefined[symbol]; ok {
+			errs = append(errs, s.errorf(offset, "%s %q redefines a predefined symbol", kind, symbol))
+			return
+		}
+		if previous, ok := declarations[symbol]; ok {
+			err := s.errorf(offset, "%s %q declared twice", kind, symbol)
+			err.Related = previous
+			errs = append(errs, err)
+			return
+		}
+		declarations[symbol] = s.errorf(offset, "first declaration of %q", symbol)
+		symbolTable[symbol] = value
+	}
+
+	for i := range statements {
+		s := &statements[i]
+		switch instruction := s.Instruction.(type) {
+		case SymbolicAInstruction, DecimalAInstruction, CInstruction:
+			address++
+		case LInstruction:
+			define(s, "label", instruction.Symbol, 1, address)
+		case EquDirective:
+			value, err := constantValue(instruction.Value, symbolTable)
+			if err != nil {
+				errs = append(errs, s.errorf(strings.Index(s.text, instruction.Value), "%v", err))
+				continue
+			}
+			define(s, "constant", instruction.Symbol, strings.Index(s.text, instruction.Symbol), value)
+		}
+	}
+	return symbolTable, errs.Err()
+}
+
+// constantValue returns the value for a .equ directive: a number, or a symbol that's defined
+// earlier in the program.
+func constantValue(text string, symbolTable map[string]uint) (uint, error) {
+	if validSymbol(text) {
+		value, ok := symbolTable[text]
+		if !ok {
+			return 0, fmt.Errorf("undefined symbol in .equ directive: %q", text)
+		}
+		return value, nil
+	}
+	value, ok := parseNumber(text)
+	if !ok {
+		return 0, fmt.Errorf("invalid value in .equ directive: '%s'", text)
+	}
+	if value > maxValue {
+		return 0, fmt.Errorf("value out of range in .equ directive: %s (maximum is %d)", text, maxValue)
+	}
+	return uint(value), nil
+}
+
+func translate(statements []Statement, symbolTable map[string]uint, l *listing) ([]uint16, error) {
+	hackWriter := NewHackWriter(nil)
+
+	// symbols declared with .extern aren't variables; without a linker, they must be labels or
+	// constants in the program
+	externs := make(map[string]bool)
+	for _, s := range statements {
+		if d, ok := s.Instruction.(ExternDirective); ok {
+			externs[d.Symbol] = true
+		}
+	}
+
+	var address uint
+	var nextAddress uint = 16
+	var errs ErrorList
+	var program []uint16
+	for i := range statements {
+		s := &statements[i]
+		var code uint16
+		switch instruction := s.Instruction.(type) {
+		case SymbolicAInstruction:
+			value, ok := symbolTable[instruction.Symbol]
+			if !ok && !externs[instruction.Symbol] {
+				value = nextAddress
+				symbolTable[instruction.Symbol] = value
+				nextAddress++
+				l.variable(instruction.Symbol)
+			}
+			code = encodeA(DecimalAInstruction{
+				Value: value,
+			})
+		case DecimalAInstruction:
+			code = encodeA(instruction)
+		case CInstruction:
+			var err error
+			code, err = hackWriter.encodeC(instruction)
+			var fieldErr *FieldError
+			if errors.As(err, &fieldErr) {
+				errs = append(errs, s.errorf(instruction.offset(fieldErr.Field), "%v", err))
+				continue
+			}
+		case LInstruction:
+			l.label(s.line, address)
+			continue
+		case EquDirective:
+			l.constant(instruction.Symbol)
+			continue
+		case ExternDirective:
+			if _, ok := symbolTable[instruction.Symbol]; !ok {
+				errs = append(errs, s.errorf(0, "undefined symbol %q", instruction.Symbol))
+			}
+			continue
+		case GlobalDirective:
+			continue
+		}
+		program = append(program, code)
+		l.instruction(s.line, address, code)
+		address++
+	}
+	return program, errs.Err()
+}
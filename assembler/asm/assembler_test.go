@@ -0,0 +1,222 @@
+package asm
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func sampleProgram(t *testing.T) []Statement {
+	source := `
+		(first)
+		M=1
+		@second
+		(second)
+		@variable
+		@first
+		@anothervariable
+	`
+	statements, errs := parse(sourceLines(t, source))
+	if len(errs) > 0 {
+		t.Fatalf("parse returned errors:\n%v", errs)
+	}
+	return statements
+}
+
+func sourceLines(t *testing.T, source string) []sourceLine {
+	t.Helper()
+	lines, err := readLines("test.asm", strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("readLines returned error: %v", err)
+	}
+	return lines
+}
+
+// symbolTableErrors parses source and runs the first pass on it, returning both parse errors and
+// errors from the first pass.
+func symbolTableErrors(t *testing.T, source string) ErrorList {
+	t.Helper()
+	statements, errs := parse(sourceLines(t, source))
+	_, err := createSymbolTable(statements)
+	var symbolErrs ErrorList
+	if !errors.As(err, &symbolErrs) && err != nil {
+		t.Fatalf("createSymbolTable returned %v, want an ErrorList", err)
+	}
+	errs = append(errs, symbolErrs...)
+	errs.Sort()
+	return errs
+}
+
+func TestCreateSymbolTable(t *testing.T) {
+	table, err := createSymbolTable(sampleProgram(t))
+	if err != nil {
+		t.Fatalf("createSymbolTable returned error: %v", err)
+	}
+	want := predefinedSymbols()
+	want["first"] = 0
+	want["second"] = 2
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("createSymbolTable returned:\n%#v\nwant:\n%#v", table, want)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	symbolTable := map[string]uint{
+		"first":  123,
+		"second": 234,
+	}
+	got, err := translate(sampleProgram(t), symbolTable, nil)
+	if err != nil {
+		t.Fatalf("translate returned error: %v", err)
+	}
+	want := []uint16{
+		0b1110111111001000,
+		0b0000000011101010,
+		0b0000000000010000,
+		0b0000000001111011,
+		0b0000000000010001,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("translate returned:\n%016b\nwant\n%016b\n", got, want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	source := "@1\n  D=DX\n(loop\n@99999\n\tAM=M;JNX\n"
+	var builder strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &builder, Options{})
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Run returned %v, want an ErrorList", err)
+	}
+	want := []string{
+		"test.asm:2:5: invalid comp field in C-instruction: \"DX\"\n\t  D=DX\n\t    ^",
+		"test.asm:3:1: invalid label declaration: '(loop'\n\t(loop\n\t^",
+		"test.asm:4:2: value out of range in A-instruction: 99999 (maximum is 32767)\n\t@99999\n\t ^",
+		"test.asm:5:7: invalid jump field in C-instruction: \"JNX\"\n\t\tAM=M;JNX\n\t\t     ^",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Run returned %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, e := range errs {
+		if e.Error() != want[i] {
+			t.Errorf("error %d is:\n%s\nwant:\n%s", i, e.Error(), want[i])
+		}
+	}
+	if builder.Len() != 0 {
+		t.Errorf("Run wrote output despite errors:\n%s", builder.String())
+	}
+}
+
+func TestRunUndefinedExtern(t *testing.T) {
+	// without a linker, an extern symbol must be declared in the program, not allocated as a
+	// variable
+	source := ".extern f\n.extern g\n@f\n0;JMP\n@g\n0;JMP\n(g)\n@g\n0;JMP\n"
+	err := Run("test.asm", strings.NewReader(source), io.Discard, Options{})
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Run returned %v, want an ErrorList", err)
+	}
+	want := "test.asm:1:1: undefined symbol \"f\"\n\t.extern f\n\t^"
+	if len(errs) != 1 || errs[0].Error() != want {
+		t.Fatalf("Run returned:\n%v\nwant:\n%s", err, want)
+	}
+}
+
+func TestCreateSymbolTableLabelErrors(t *testing.T) {
+	source := "(loop)\n@loop\n(SP)\n(R3)\n(123 bad)\n  (loop)\n"
+	errs := symbolTableErrors(t, source)
+	want := []string{
+		"test.asm:3:2: label \"SP\" redefines a predefined symbol",
+		"test.asm:4:2: label \"R3\" redefines a predefined symbol",
+		"test.asm:5:2: invalid label name: '123 bad'",
+		"test.asm:6:4: label \"loop\" declared twice",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), errs)
+	}
+	for i, e := range errs {
+		got := e.Pos.String() + ": " + e.Msg
+		if got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+	if want := "\t(123 bad)\n\t ^"; !strings.HasSuffix(errs[2].Error(), want) {
+		t.Errorf("invalid label error is:\n%s\nwant the caret at the name", errs[2].Error())
+	}
+	related := errs[3].Related
+	if related == nil || related.Pos != (Pos{"test.asm", 1, 2}) {
+		t.Errorf("duplicate label error points at %v, want test.asm:1:2", related)
+	}
+}
+
+func TestConstants(t *testing.T) {
+	source := `
+.equ WIDTH 32
+.equ VRAM SCREEN
+.equ MASK 0xff
+    @WIDTH
+    @VRAM
+    @MASK
+    @x
+`
+	var output strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := `0000000000100000
+0100000000000000
+0000000011111111
+0000000000010000
+`
+	got := output.String()
+	if got != want {
+		t.Errorf("Run produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConstantErrors(t *testing.T) {
+	source := `.equ A1 40000
+.equ A2 LATER
+.equ SP 1
+.equ A3 1
+.equ A3 2
+.equ A4
+(LATER)
+`
+	errs := symbolTableErrors(t, source)
+	want := []string{
+		"test.asm:1:9: value out of range in .equ directive: 40000 (maximum is 32767)",
+		"test.asm:2:9: undefined symbol in .equ directive: \"LATER\"",
+		"test.asm:3:6: constant \"SP\" redefines a predefined symbol",
+		"test.asm:5:6: constant \"A3\" declared twice",
+		"test.asm:6:1: invalid .equ directive (expected .equ NAME value): '.equ A4'",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), errs)
+	}
+	for i, e := range errs {
+		got := e.Pos.String() + ": " + e.Msg
+		if got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestRunOneByteReader(t *testing.T) {
+	// Run reads its input only once, so it works on pipes and other readers that can't seek
+	source := "(loop)\n@loop\n0;JMP\n"
+	var output strings.Builder
+	err := Run("test.asm", iotest.OneByteReader(strings.NewReader(source)), &output, Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := "0000000000000000\n1110101010000111\n"
+	if got := output.String(); got != want {
+		t.Errorf("Run produced:\n%s\nwant:\n%s", got, want)
+	}
+}
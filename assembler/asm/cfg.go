@@ -0,0 +1,294 @@
+package asm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A CFG is the control-flow graph of a Hack program: its basic blocks and the possible jumps
+// between them.
+type CFG struct {
+	Blocks []*Block `json:"blocks"`
+}
+
+// A Block is a basic block: a sequence of instructions that's only entered at the start and only
+// left at the end.
+type Block struct {
+	ID           int           `json:"id"`
+	Start        uint          `json:"start"` // ROM address of the first instruction
+	End          uint          `json:"end"`   // ROM address after the last instruction
+	Labels       []string      `json:"labels,omitempty"`
+	Instructions []Instruction `json:"-"`
+	Edges        []Edge        `json:"edges,omitempty"`
+
+	// Reachable is false for blocks that no path from the start of the program leads to.
+	Reachable bool `json:"reachable"`
+}
+
+// An EdgeKind says how control gets from one block to another.
+type EdgeKind int
+
+const (
+	// EdgeFallthrough goes to the next block without a jump.
+	EdgeFallthrough EdgeKind = iota
+
+	// EdgeJump is a jump to an address loaded by an A-instruction in the same block.
+	EdgeJump
+
+	// EdgeIndirect is a jump to a computed address, like the "A=M;JMP" at the end of a VM
+	// function. It leads to every block whose address is used in the program other than for a
+	// direct jump, since those are the places a computed address can come from.
+	EdgeIndirect
+)
+
+var edgeKindNames = []string{"fallthrough", "jump", "indirect"}
+
+func (k EdgeKind) String() string {
+	return edgeKindNames[k]
+}
+
+// MarshalText implements encoding.TextMarshaler, so edge kinds are written as names in JSON.
+func (k EdgeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// An Edge leads from one block to another.
+type Edge struct {
+	To   int      `json:"to"` // ID of the block
+	Kind EdgeKind `json:"kind"`
+}
+
+// BuildCFG builds the control-flow graph for a program. Blocks start at labels, at jump targets,
+// and after C-instructions with a jump. Jump targets are resolved through the symbol table when the
+// A-instruction that loads them is in the same block as the jump; other jumps are indirect.
+func BuildCFG(statements []Statement) (*CFG, error) {
+	symbolTable, err := createSymbolTable(statements)
+	if err != nil {
+		return nil, err
+	}
+
+	// collect the instructions and the labels at each address
+	var instructions []Instruction
+	labels := make(map[uint][]string)
+	for _, s := range statements {
+		switch instruction := s.Instruction.(type) {
+		case SymbolicAInstruction, DecimalAInstruction, CInstruction:
+			instructions = append(instructions, instruction)
+		case LInstruction:
+			address := uint(len(instructions))
+			labels[address] = append(labels[address], instruction.Symbol)
+		}
+	}
+	size := uint(len(instructions))
+
+	// value returns the value loaded by an A-instruction, if it's the address of an instruction or
+	// the address right after the program
+	value := func(i Instruction) (uint, bool) {
+		var v uint
+		switch i := i.(type) {
+		case SymbolicAInstruction:
+			var ok bool
+			if v, ok = symbolTable[i.Symbol]; !ok {
+				return 0, false
+			}
+		case DecimalAInstruction:
+			v = i.Value
+		default:
+			return 0, false
+		}
+		return v, v <= size
+	}
+
+	// Find the leaders (first instructions of blocks) and the target of each jump. A is unknown at
+	// a leader, since control can come from elsewhere, but a jump target can be a leader that
+	// comes before the jump, like a numeric address for a loop. So this repeats until it finds no
+	// new leaders, and the last pass resolves jumps knowing all of them.
+	leaders := map[uint]bool{0: true}
+	for address := range labels {
+		leaders[address] = true
+	}
+	var targets map[uint]uint // by address of the jump
+	var jumpLoads map[uint]bool
+	for n := 0; n != len(leaders); {
+		n = len(leaders)
+		targets = make(map[uint]uint)
+		jumpLoads = make(map[uint]bool)
+		var a Instruction // the A-instruction that set A, or nil if A is unknown
+		var aAddress uint
+		for address, instruction := range instructions {
+			address := uint(address)
+			if leaders[address] {
+				a = nil
+			}
+			c, ok := instruction.(CInstruction)
+			if !ok {
+				a, aAddress = instruction, address
+				continue
+			}
+			if c.Jump != "" {
+				leaders[address+1] = true
+				if v, ok := value(a); ok && !strings.Contains(c.Dest, "A") {
+					targets[address] = v
+					leaders[v] = true
+					jumpLoads[aAddress] = true
+				}
+			}
+			if strings.Contains(c.Dest, "A") || c.Jump != "" {
+				a = nil
+			}
+		}
+	}
+
+	// Labels that are loaded other than for a direct jump, like a return address that's pushed
+	// onto the stack, are where indirect jumps can go.
+	addressTaken := make(map[uint]bool)
+	isLabel := make(map[string]bool)
+	for _, symbols := range labels {
+		for _, symbol := range symbols {
+			isLabel[symbol] = true
+		}
+	}
+	for address, instruction := range instructions {
+		i, ok := instruction.(SymbolicAInstruction)
+		if ok && isLabel[i.Symbol] && !jumpLoads[uint(address)] {
+			addressTaken[symbolTable[i.Symbol]] = true
+		}
+	}
+
+	// create the blocks
+	g := &CFG{}
+	blockAt := make(map[uint]*Block)
+	for address := uint(0); address < size; address++ {
+		if leaders[address] {
+			b := &Block{ID: len(g.Blocks), Start: address, Labels: labels[address]}
+			g.Blocks = append(g.Blocks, b)
+			blockAt[address] = b
+		}
+		b := g.Blocks[len(g.Blocks)-1]
+		b.Instructions = append(b.Instructions, instructions[address])
+		b.End = address + 1
+	}
+
+	// add the edges
+	var indirectTargets []int
+	for _, b := range g.Blocks {
+		if addressTaken[b.Start] {
+			indirectTargets = append(indirectTargets, b.ID)
+		}
+	}
+	for _, b := range g.Blocks {
+		last := b.End - 1
+		c, isC := instructions[last].(CInstruction)
+		if !isC || c.Jump != "JMP" {
+			if next, ok := blockAt[b.End]; ok {
+				b.Edges = append(b.Edges, Edge{next.ID, EdgeFallthrough})
+			}
+		}
+		if !isC || c.Jump == "" {
+			continue
+		}
+		if target, ok := targets[last]; ok {
+			// a jump to the end of the program doesn't lead to a block
+			if next, ok := blockAt[target]; ok {
+				b.Edges = append(b.Edges, Edge{next.ID, EdgeJump})
+			}
+		} else {
+			for _, id := range indirectTargets {
+				b.Edges = append(b.Edges, Edge{id, EdgeIndirect})
+			}
+		}
+	}
+
+	// find the reachable blocks
+	if len(g.Blocks) > 0 {
+		work := []*Block{g.Blocks[0]}
+		g.Blocks[0].Reachable = true
+		for len(work) > 0 {
+			b := work[len(work)-1]
+			work = work[:len(work)-1]
+			for _, e := range b.Edges {
+				if next := g.Blocks[e.To]; !next.Reachable {
+					next.Reachable = true
+					work = append(work, next)
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// Unreachable returns the blocks that can't be reached from the start of the program.
+func (g *CFG) Unreachable() []*Block {
+	var blocks []*Block
+	for _, b := range g.Blocks {
+		if !b.Reachable {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Unreachable blocks are grayed out,
+// jumps are drawn as solid edges, fallthrough as dotted edges, and indirect jumps as dashed edges.
+func (g *CFG) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph cfg {")
+	fmt.Fprintln(bw, "\tnode [shape=box, fontname=\"monospace\"];")
+	for _, b := range g.Blocks {
+		var label strings.Builder
+		fmt.Fprintf(&label, "%d-%d", b.Start, b.End-1)
+		for _, l := range b.Labels {
+			fmt.Fprintf(&label, "\\l(%s)", dotEscape(l))
+		}
+		for _, i := range b.Instructions {
+			fmt.Fprintf(&label, "\\l    %s", dotEscape(i.String()))
+		}
+		label.WriteString("\\l")
+		attributes := ""
+		if !b.Reachable {
+			attributes = ", style=filled, fillcolor=lightgray"
+		}
+		fmt.Fprintf(bw, "\tb%d [label=\"%s\"%s];\n", b.ID, label.String(), attributes)
+	}
+	for _, b := range g.Blocks {
+		for _, e := range b.Edges {
+			style := ""
+			switch e.Kind {
+			case EdgeFallthrough:
+				style = " [style=dotted]"
+			case EdgeIndirect:
+				style = " [style=dashed]"
+			}
+			fmt.Fprintf(bw, "\tb%d -> b%d%s;\n", b.ID, e.To, style)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// WriteJSON writes the graph as JSON.
+func (g *CFG) WriteJSON(w io.Writer) error {
+	type jsonBlock struct {
+		*Block
+		Instructions []string `json:"instructions"`
+	}
+	blocks := make([]jsonBlock, len(g.Blocks))
+	for i, b := range g.Blocks {
+		blocks[i] = jsonBlock{b, make([]string, len(b.Instructions))}
+		for j, instruction := range b.Instructions {
+			blocks[i].Instructions[j] = instruction.String()
+		}
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(struct {
+		Blocks []jsonBlock `json:"blocks"`
+	}{blocks})
+}
@@ -0,0 +1,121 @@
+package asm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func buildCFG(t *testing.T, source string) *CFG {
+	t.Helper()
+	statements, err := Parse("test.asm", strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	g, err := BuildCFG(statements)
+	if err != nil {
+		t.Fatalf("BuildCFG returned error: %v", err)
+	}
+	return g
+}
+
+func TestBuildCFG(t *testing.T) {
+	source := `
+    // call f, passing the return address in R13
+    @RET
+    D=A
+    @R13
+    M=D
+    @f
+    0;JMP
+(RET)
+    @END
+    0;JMP
+    D=0      // unreachable
+(f)
+    @R0
+    D=M
+    @f
+    D;JGT
+    @R13
+    A=M
+    0;JMP
+(END)
+    @END
+    0;JMP
+`
+	g := buildCFG(t, source)
+	type block struct {
+		start, end uint
+		labels     []string
+		edges      []Edge
+		reachable  bool
+	}
+	var got []block
+	for _, b := range g.Blocks {
+		got = append(got, block{b.Start, b.End, b.Labels, b.Edges, b.Reachable})
+	}
+	want := []block{
+		{0, 6, nil, []Edge{{3, EdgeJump}}, true},
+		{6, 8, []string{"RET"}, []Edge{{5, EdgeJump}}, true},
+		{8, 9, nil, []Edge{{3, EdgeFallthrough}}, false},
+		{9, 13, []string{"f"}, []Edge{{4, EdgeFallthrough}, {3, EdgeJump}}, true},
+		{13, 16, nil, []Edge{{1, EdgeIndirect}}, true},
+		{16, 18, []string{"END"}, []Edge{{5, EdgeJump}}, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildCFG returned blocks:\n%v\nwant:\n%v", got, want)
+	}
+	if unreachable := g.Unreachable(); len(unreachable) != 1 || unreachable[0].ID != 2 {
+		t.Errorf("Unreachable returned %v, want block 2", unreachable)
+	}
+}
+
+func TestBuildCFGBackwardTarget(t *testing.T) {
+	// the jump at 4 goes back to 1, so the block starting there doesn't know A: when the loop
+	// runs, A is 1 at the conditional jump, not 7
+	source := "@7\nD=D-1\nD;JGT\n@1\n0;JMP\n(END)\n@END\n0;JMP\nD=0\n"
+	g := buildCFG(t, source)
+	var starts []uint
+	for _, b := range g.Blocks {
+		starts = append(starts, b.Start)
+	}
+	if want := []uint{0, 1, 3, 5, 7}; !reflect.DeepEqual(starts, want) {
+		t.Fatalf("BuildCFG returned blocks starting at %v, want %v", starts, want)
+	}
+	if want := []Edge{{2, EdgeFallthrough}}; !reflect.DeepEqual(g.Blocks[1].Edges, want) {
+		t.Errorf("block 1 has edges %v, want %v", g.Blocks[1].Edges, want)
+	}
+	if g.Blocks[4].Reachable {
+		t.Errorf("block 4 is reachable, want unreachable")
+	}
+}
+
+func TestCFGOutput(t *testing.T) {
+	g := buildCFG(t, "(LOOP)\n@LOOP\nD;JGT\nA=M;JMP\n")
+	var dot strings.Builder
+	if err := g.WriteDOT(&dot); err != nil {
+		t.Fatalf("WriteDOT returned error: %v", err)
+	}
+	wantDOT := `digraph cfg {
+	node [shape=box, fontname="monospace"];
+	b0 [label="0-1\l(LOOP)\l    @LOOP\l    D;JGT\l"];
+	b1 [label="2-2\l    A=M;JMP\l"];
+	b0 -> b1 [style=dotted];
+	b0 -> b0;
+}
+`
+	if got := dot.String(); got != wantDOT {
+		t.Errorf("WriteDOT wrote:\n%s\nwant:\n%s", got, wantDOT)
+	}
+
+	var js strings.Builder
+	if err := g.WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+	for _, s := range []string{`"labels": [`, `"kind": "fallthrough"`, `"instructions": [`, `"A=M;JMP"`} {
+		if !strings.Contains(js.String(), s) {
+			t.Errorf("WriteJSON output doesn't contain %s:\n%s", s, js.String())
+		}
+	}
+}
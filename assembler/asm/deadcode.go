@@ -0,0 +1,262 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Registers that liveness analysis keeps track of, as bits in a set.
+const (
+	regA = 1 << iota
+	regD
+)
+
+// registers returns the registers an instruction reads and writes. A C-instruction reads A when it
+// computes with A or M, stores to M, or jumps, since all of those use A as an address. A jump goes
+// to the value A has after the instruction, so a jump that also writes A, like "A=M;JMP", reads the
+// value it writes rather than the one A had before; see liveAfter.
+func registers(instruction Instruction) (uses, defs int) {
+	c, ok := instruction.(CInstruction)
+	if !ok {
+		return 0, regA
+	}
+	if strings.ContainsAny(c.Comp, "AM") || strings.Contains(c.Dest, "M") ||
+		c.Jump != "" && !strings.Contains(c.Dest, "A") {
+		uses |= regA
+	}
+	if strings.Contains(c.Comp, "D") {
+		uses |= regD
+	}
+	if strings.Contains(c.Dest, "A") {
+		defs |= regA
+	}
+	if strings.Contains(c.Dest, "D") {
+		defs |= regD
+	}
+	return uses, defs
+}
+
+// liveAfter returns the registers whose values written by an instruction are read, given the
+// registers live after it: a jump reads the A it has just written.
+func liveAfter(instruction Instruction, live int) int {
+	if c, ok := instruction.(CInstruction); ok && c.Jump != "" {
+		live |= regA
+	}
+	return live
+}
+
+// DeadCode looks for code in a program that has no effect and returns what it finds as warnings,
+// sorted by position:
+//
+//   - instructions that can't be reached from the start of the program
+//   - values loaded into A or D that are never read, because they're overwritten first or the
+//     program ends
+//   - stores to variables that are never read
+//
+// The analysis works on the control-flow graph built by BuildCFG, so it's only as good as the
+// graph: indirect jumps are assumed to go to any block whose address the program loads.
+func DeadCode(statements []Statement) (ErrorList, error) {
+	g, err := BuildCFG(statements)
+	if err != nil {
+		return nil, err
+	}
+	symbolTable, err := createSymbolTable(statements)
+	if err != nil {
+		return nil, err
+	}
+
+	// the statement for each instruction, by address
+	var code []*Statement
+	for i := range statements {
+		switch statements[i].Instruction.(type) {
+		case SymbolicAInstruction, DecimalAInstruction, CInstruction:
+			code = append(code, &statements[i])
+		}
+	}
+
+	var errs ErrorList
+	errs = append(errs, unreachableCode(g, code)...)
+	errs = append(errs, deadWrites(g, code)...)
+	errs = append(errs, deadStores(g, statements, code, symbolTable)...)
+	errs.Sort()
+	return errs, nil
+}
+
+// unreachableCode reports each run of unreachable blocks once, at its first instruction.
+func unreachableCode(g *CFG, code []*Statement) ErrorList {
+	var errs ErrorList
+	for i := 0; i < len(g.Blocks); i++ {
+		if g.Blocks[i].Reachable {
+			continue
+		}
+		start := g.Blocks[i].Start
+		for i+1 < len(g.Blocks) && !g.Blocks[i+1].Reachable {
+			i++
+		}
+		n := g.Blocks[i].End - start
+		errs = append(errs, warningf(code[start], 0, "unreachable code (%d %s)", n, plural(n, "instruction")))
+	}
+	return errs
+}
+
+// deadWrites reports instructions in reachable blocks that write A or D when the value is never
+// read. It's a standard backward liveness analysis: a register is live after an instruction if
+// some path from there reads it before writing it.
+func deadWrites(g *CFG, code []*Statement) ErrorList {
+	// transfer computes the registers live before the instructions from start to end, given the
+	// registers live after them
+	transfer := func(start, end uint, live int) int {
+		for address := end; address > start; address-- {
+			instruction := code[address-1].Instruction
+			uses, defs := registers(instruction)
+			live = liveAfter(instruction, live)&^defs | uses
+		}
+		return live
+	}
+
+	liveIn := make([]int, len(g.Blocks))
+	liveOut := make([]int, len(g.Blocks))
+	for changed := true; changed; {
+		changed = false
+		for i := len(g.Blocks) - 1; i >= 0; i-- {
+			b := g.Blocks[i]
+			out := 0
+			for _, e := range b.Edges {
+				out |= liveIn[e.To]
+			}
+			in := transfer(b.Start, b.End, out)
+			if in != liveIn[i] || out != liveOut[i] {
+				liveIn[i], liveOut[i] = in, out
+				changed = true
+			}
+		}
+	}
+
+	var errs ErrorList
+	for i, b := range g.Blocks {
+		if !b.Reachable {
+			continue
+		}
+		live := liveOut[i]
+		for address := b.End; address > b.Start; address-- {
+			s := code[address-1]
+			uses, defs := registers(s.Instruction)
+			live = liveAfter(s.Instruction, live)
+			if dead := defs &^ live; dead != 0 {
+				if _, ok := s.Instruction.(CInstruction); ok {
+					errs = append(errs, warningf(s, 0, "value written to %s is never read", registerNames(dead)))
+				} else {
+					errs = append(errs, warningf(s, 0, "value loaded into A is never used"))
+				}
+			}
+			live = live&^defs | uses
+		}
+	}
+	return errs
+}
+
+func registerNames(registers int) string {
+	switch registers {
+	case regA:
+		return "A"
+	case regD:
+		return "D"
+	}
+	return "A and D"
+}
+
+// deadStores reports stores to variables in reachable blocks when the variable is never read.
+// A variable's address is known to be in A after an A-instruction that loads it, until the next
+// instruction that writes A. A variable whose address is used any other way, for example copied to
+// D, might be read through a pointer, so it's left out.
+func deadStores(g *CFG, statements []Statement, code []*Statement, symbolTable map[string]uint) ErrorList {
+	// allocate variables the way the assembler does, so we can tell which addresses are theirs
+	variables := make(map[uint]string)
+	var nextAddress uint = 16
+	escaped := make(map[string]bool)
+	for _, s := range statements {
+		// symbols shared with other object files might be read there
+		switch i := s.Instruction.(type) {
+		case GlobalDirective:
+			escaped[i.Symbol] = true
+		case ExternDirective:
+			escaped[i.Symbol] = true
+		}
+	}
+	for _, s := range code {
+		if i, ok := s.Instruction.(SymbolicAInstruction); ok {
+			if _, ok := symbolTable[i.Symbol]; !ok {
+				symbolTable[i.Symbol] = nextAddress
+				variables[nextAddress] = i.Symbol
+				nextAddress++
+			}
+		}
+	}
+	// a constant or number with a variable's address is a way to access it we don't track
+	for _, s := range code {
+		var address uint
+		switch i := s.Instruction.(type) {
+		case SymbolicAInstruction:
+			if variables[symbolTable[i.Symbol]] == i.Symbol {
+				continue
+			}
+			address = symbolTable[i.Symbol]
+		case DecimalAInstruction:
+			address = i.Value
+		default:
+			continue
+		}
+		if variable, ok := variables[address]; ok {
+			escaped[variable] = true
+		}
+	}
+
+	read := make(map[string]bool)
+	var stores []*Statement
+	var storeVariables []string
+	for _, b := range g.Blocks {
+		variable := "" // the variable whose address is in A, if known
+		for address := b.Start; address < b.End; address++ {
+			s := code[address]
+			c, ok := s.Instruction.(CInstruction)
+			if !ok {
+				variable = ""
+				if i, ok := s.Instruction.(SymbolicAInstruction); ok && variables[symbolTable[i.Symbol]] == i.Symbol {
+					variable = i.Symbol
+				}
+				continue
+			}
+			if variable != "" {
+				if strings.Contains(c.Comp, "A") || c.Jump != "" {
+					escaped[variable] = true
+				}
+				if strings.Contains(c.Comp, "M") {
+					read[variable] = true
+				}
+				if strings.Contains(c.Dest, "M") && b.Reachable {
+					stores = append(stores, s)
+					storeVariables = append(storeVariables, variable)
+				}
+			}
+			if strings.Contains(c.Dest, "A") {
+				variable = ""
+			}
+		}
+	}
+
+	var errs ErrorList
+	for i, s := range stores {
+		variable := storeVariables[i]
+		if !read[variable] && !escaped[variable] {
+			errs = append(errs, warningf(s, 0, "store to variable %q, which is never read", variable))
+		}
+	}
+	return errs
+}
+
+func plural(n uint, word string) string {
+	if n == 1 {
+		return word
+	}
+	return fmt.Sprintf("%ss", word)
+}
@@ -0,0 +1,67 @@
+package asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeadCode(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{
+			// nothing to report
+			"@R0\nD=M\n@x\nM=D\n@x\nD=M\n@R1\nM=D\n(END)\n@END\n0;JMP\n",
+			nil,
+		},
+		{
+			"@R0\nD=M\n@R1\nM=D\n@END\n0;JMP\nD=0\n@R1\nM=D\n(END)\n@END\n0;JMP\n",
+			[]string{"test.asm:7:1: warning: unreachable code (3 instructions)"},
+		},
+		{
+			"@5\nD=A\n@R0\nD=M\n@R1\n@R2\nM=D\n(END)\n@END\n0;JMP\n",
+			[]string{
+				"test.asm:2:1: warning: value written to D is never read",
+				"test.asm:5:1: warning: value loaded into A is never used",
+			},
+		},
+		{
+			// stores to a variable that's never read
+			"@R0\nD=M\n@x\nM=D\n@y\nM=D\n@y\nD=M\n@R1\nM=D\n(END)\n@END\n0;JMP\n",
+			[]string{"test.asm:4:1: warning: store to variable \"x\", which is never read"},
+		},
+		{
+			// a variable whose address escapes may be read through a pointer
+			"@x\nD=A\n@R0\nM=D\n@x\nM=0\n(END)\n@END\n0;JMP\n",
+			nil,
+		},
+		{
+			// a jump reads the value it writes to A, as in the return from a VM function
+			"@RET\nD=A\n@R14\nM=D\n@f\n0;JMP\n(RET)\n@END\n0;JMP\n(f)\n@R14\nA=M;JMP\n(END)\n@END\n0;JMP\n",
+			nil,
+		},
+		{
+			// the return address in D is read after the indirect jump
+			"@RET\nD=A\n@f\n0;JMP\n(RET)\n@END\n0;JMP\n(f)\n@R13\nM=D\nA=M\n0;JMP\n(END)\n@END\n0;JMP\n",
+			nil,
+		},
+	}
+	for _, test := range tests {
+		statements, err := Parse("test.asm", strings.NewReader(test.source))
+		if err != nil {
+			t.Fatalf("Parse returned error: %v", err)
+		}
+		errs, err := DeadCode(statements)
+		if err != nil {
+			t.Fatalf("DeadCode returned error: %v", err)
+		}
+		var got []string
+		for _, e := range errs {
+			got = append(got, strings.SplitN(e.Error(), "\n", 2)[0])
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("DeadCode(%q) returned:\n%s\nwant:\n%s", test.source, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
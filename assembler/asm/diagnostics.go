@@ -0,0 +1,99 @@
+package asm
+
+// romSize is the number of instructions the Hack ROM holds.
+const romSize = 1 << 15
+
+// DefaultVariableLimit is the highest RAM address the assembler allocates variables at without a
+// warning, unless Options.VariableLimit says otherwise. Addresses from 256 on are used for the VM
+// stack.
+const DefaultVariableLimit = 255
+
+// diagnose looks for problems with the memory layout of a program that has been translated
+// without errors, and for symbols that are probably mistakes:
+//
+//   - more instructions than fit in ROM (an error)
+//   - symbols with addresses too large for an A-instruction (an error)
+//   - variables allocated past variableLimit, where they'd overlap with the stack or the screen
+//   - labels that are referenced only once or never
+//
+// All but the errors are reported as warnings.
+func diagnose(statements []Statement, symbolTable map[string]uint, variableLimit uint) ErrorList {
+	var errs ErrorList
+	predefined := predefinedSymbols()
+	declared := make(map[string]bool)
+	references := make(map[string][]*Statement)
+	exported := make(map[string]bool)
+	var symbols []string
+	var address int
+	for i := range statements {
+		s := &statements[i]
+		switch instruction := s.Instruction.(type) {
+		case SymbolicAInstruction:
+			symbol := instruction.Symbol
+			if _, ok := predefined[symbol]; !ok && references[symbol] == nil {
+				symbols = append(symbols, symbol)
+			}
+			references[symbol] = append(references[symbol], s)
+		case LInstruction:
+			declared[instruction.Symbol] = true
+		case EquDirective:
+			declared[instruction.Symbol] = true
+		case GlobalDirective:
+			// exported labels are used by other object files
+			exported[instruction.Symbol] = true
+		}
+		switch s.Instruction.(type) {
+		case SymbolicAInstruction, DecimalAInstruction, CInstruction:
+			if address == romSize {
+				errs = append(errs, s.errorf(0, "program too large: ROM holds %d instructions", romSize))
+			}
+			address++
+		}
+	}
+
+	for _, symbol := range symbols {
+		first := references[symbol][0]
+		value := symbolTable[symbol]
+		if declared[symbol] {
+			// a label right after the last instruction of a full ROM
+			if value > maxValue {
+				errs = append(errs, first.errorf(1, "address of %q doesn't fit in an A-instruction: %d", symbol, value))
+			}
+			continue
+		}
+		switch {
+		case value > maxValue:
+			errs = append(errs, first.errorf(1, "no RAM left for variable %q", symbol))
+		case value > variableLimit:
+			errs = append(errs, warningf(first, 1, "variable %q allocated at RAM[%d], past the limit of %d", symbol, value, variableLimit))
+		}
+	}
+
+	for i := range statements {
+		s := &statements[i]
+		label, ok := s.Instruction.(LInstruction)
+		// labels in macro expansions are left out: a macro may declare a label that only some
+		// of its expansions use
+		if !ok || s.source.call != nil {
+			continue
+		}
+		switch refs := references[label.Symbol]; {
+		case exported[label.Symbol]:
+			// other object files may refer to it
+		case len(refs) == 0:
+			errs = append(errs, warningf(s, 1, "label %q is never referenced", label.Symbol))
+		case len(refs) == 1:
+			e := warningf(s, 1, "label %q is referenced only once", label.Symbol)
+			e.Related = refs[0].errorf(1, "only reference to %q", label.Symbol)
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
+
+// warningf is like Statement.errorf, but returns a warning.
+func warningf(s *Statement, offset int, format string, a ...any) *Error {
+	e := s.errorf(offset, format, a...)
+	e.Warning = true
+	return e
+}
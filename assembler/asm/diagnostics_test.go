@@ -0,0 +1,114 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// diagnostics assembles source and returns what diagnose reports for it.
+func diagnostics(t *testing.T, source string, variableLimit uint) ErrorList {
+	t.Helper()
+	statements, errs := parse(sourceLines(t, source))
+	if len(errs) > 0 {
+		t.Fatalf("parse returned errors:\n%v", errs)
+	}
+	symbolTable, err := createSymbolTable(statements)
+	if err != nil {
+		t.Fatalf("createSymbolTable returned error: %v", err)
+	}
+	if _, err := translate(statements, symbolTable, nil); err != nil {
+		t.Fatalf("translate returned error: %v", err)
+	}
+	return diagnose(statements, symbolTable, variableLimit)
+}
+
+func TestDiagnose(t *testing.T) {
+	source := `(START)
+(UNUSED)
+    @a
+    M=0
+    @b
+    M=0
+    @a
+    D=M
+    @START
+    0;JMP
+`
+	errs := diagnostics(t, source, 16)
+	want := []string{
+		"test.asm:1:2: warning: label \"START\" is referenced only once",
+		"test.asm:2:2: warning: label \"UNUSED\" is never referenced",
+		"test.asm:5:6: warning: variable \"b\" allocated at RAM[17], past the limit of 16",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("diagnose returned %d errors, want %d:\n%v", len(errs), len(want), errs)
+	}
+	errs.Sort()
+	for i, e := range errs {
+		got := strings.SplitN(e.Error(), "\n", 2)[0]
+		if got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestDiagnoseLabelReferences(t *testing.T) {
+	source := ".global MAIN\n(MAIN)\n(LOOP)\n    @LOOP\n    0;JMP\n"
+	errs := diagnostics(t, source, DefaultVariableLimit)
+	if len(errs) != 1 {
+		t.Fatalf("diagnose returned %d errors, want 1:\n%v", len(errs), errs)
+	}
+	want := "test.asm:3:2: warning: label \"LOOP\" is referenced only once"
+	if got := strings.SplitN(errs[0].Error(), "\n", 2)[0]; got != want {
+		t.Errorf("diagnose returned %q, want %q", got, want)
+	}
+	related := errs[0].Related
+	if related == nil || related.Pos.String() != "test.asm:4:6" || related.Msg != "only reference to \"LOOP\"" {
+		t.Errorf("warning has related error %v, want one pointing at the reference", related)
+	}
+}
+
+func TestDiagnoseROMOverflow(t *testing.T) {
+	source := "(START)\n" + strings.Repeat("D=0\n", romSize) + "(END)\n@END\n@START\n@END\n@START\n0;JMP\n"
+	errs := diagnostics(t, source, DefaultVariableLimit)
+	line := romSize + 3
+	want := []string{
+		fmt.Sprintf("test.asm:%d:1: program too large: ROM holds 32768 instructions", line),
+		fmt.Sprintf("test.asm:%d:2: address of \"END\" doesn't fit in an A-instruction: 32768", line),
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("diagnose returned %d errors, want %d:\n%v", len(errs), len(want), errs)
+	}
+	errs.Sort()
+	for i, e := range errs {
+		got := strings.SplitN(e.Error(), "\n", 2)[0]
+		if got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestRunStrict(t *testing.T) {
+	source := "(UNUSED)\n@0\n"
+	var output, warnings strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{Warnings: &warnings})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := "test.asm:1:2: warning: label \"UNUSED\" is never referenced\n\t(UNUSED)\n\t ^\n"
+	if warnings.String() != want {
+		t.Errorf("Run wrote warnings:\n%s\nwant:\n%s", warnings.String(), want)
+	}
+
+	output.Reset()
+	err = Run("test.asm", strings.NewReader(source), &output, Options{Strict: true})
+	var errs ErrorList
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Warning {
+		t.Fatalf("Run with Strict returned %v, want one error", err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("Run with Strict wrote output despite errors:\n%s", output.String())
+	}
+}
@@ -0,0 +1,212 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Disassemble reads a binary Hack program (a .hack file) from r and writes equivalent Hack assembly
+// to w. Filename is used in error messages.
+//
+// If labels is true, A-instructions that load the target of a jump are replaced by symbolic ones
+// and a label is declared at each jump target, so the output is easier to read but still
+// assembles to the same binary program.
+//
+// Words that aren't valid Hack instructions are written as comments and reported in the returned
+// ErrorList; the rest of the program is still written.
+//
+// Files ending in .bin are read in the raw format, as written with FormatRaw; others in the text
+// format used by the course tools. For raw files, error messages give the position of the word in
+// the file as the line number.
+func Disassemble(filename string, r io.Reader, w io.Writer, labels bool) error {
+	var program []uint16
+	var lines []int
+	var err error
+	if filepath.Ext(filename) == FormatRaw.Extension() {
+		program, err = readRaw(r)
+		for i := range program {
+			lines = append(lines, i+1)
+		}
+	} else {
+		program, lines, err = readHack(filename, r)
+	}
+	if err != nil {
+		return err
+	}
+
+	d := newDecoder()
+	instructions := make([]Instruction, len(program))
+	var errs ErrorList
+	for i, word := range program {
+		instructions[i], err = d.decode(word)
+		if err != nil {
+			errs = append(errs, &Error{
+				Pos: Pos{File: filename, Line: lines[i], Column: 1},
+				Msg: err.Error(),
+			})
+		}
+	}
+
+	targets := make(map[int]string)
+	if labels {
+		targets = jumpTargets(instructions)
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, instruction := range instructions {
+		if label, ok := targets[i]; ok {
+			fmt.Fprintf(bw, "(%s)\n", label)
+		}
+		switch instruction := instruction.(type) {
+		case DecimalAInstruction:
+			label, ok := targets[int(instruction.Value)]
+			if ok && isJump(instructions, i+1) {
+				fmt.Fprintf(bw, "    @%s\n", label)
+			} else {
+				fmt.Fprintf(bw, "    @%d\n", instruction.Value)
+			}
+		case CInstruction:
+			fmt.Fprintf(bw, "    %s\n", instruction)
+		default:
+			fmt.Fprintf(bw, "    // invalid instruction: %016b\n", program[i])
+		}
+	}
+	if label, ok := targets[len(instructions)]; ok {
+		fmt.Fprintf(bw, "(%s)\n", label)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return errs.Err()
+}
+
+// readHack reads a binary Hack program with one instruction per line, written as 16 '0' and '1'
+// characters. Along with the program, it returns the line number of each instruction.
+func readHack(filename string, r io.Reader) ([]uint16, []int, error) {
+	var program []uint16
+	var lines []int
+	var errs ErrorList
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var word uint16
+		valid := len(text) == 16
+		for _, c := range text {
+			word <<= 1
+			switch c {
+			case '0':
+			case '1':
+				word |= 1
+			default:
+				valid = false
+			}
+		}
+		if !valid {
+			errs = append(errs, &Error{
+				Pos:    Pos{File: filename, Line: line, Column: 1},
+				Msg:    "expected 16 binary digits",
+				Source: scanner.Text(),
+			})
+			continue
+		}
+		program = append(program, word)
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return program, lines, errs.Err()
+}
+
+// jumpTargets finds the ROM addresses that are targets of a jump and returns a label for each.
+func jumpTargets(instructions []Instruction) map[int]string {
+	targets := make(map[int]string)
+	for i, instruction := range instructions {
+		a, ok := instruction.(DecimalAInstruction)
+		if !ok || !isJump(instructions, i+1) {
+			continue
+		}
+		// a label can go anywhere in the program, including right after the last instruction
+		if target := int(a.Value); target <= len(instructions) {
+			targets[target] = fmt.Sprintf("L%d", target)
+		}
+	}
+	return targets
+}
+
+// isJump reports whether the instruction at index i is a C-instruction with a jump that doesn't
+// write to A, so it jumps to the address loaded by the A-instruction before it.
+func isJump(instructions []Instruction, i int) bool {
+	if i >= len(instructions) {
+		return false
+	}
+	c, ok := instructions[i].(CInstruction)
+	return ok && c.Jump != "" && !strings.Contains(c.Dest, "A")
+}
+
+// decoderTables is used by Decode.
+var decoderTables = newDecoder()
+
+// Decode returns the DecimalAInstruction or CInstruction for a 16-bit word. It's the inverse of
+// Encode, except that it writes dest fields in the conventional order, like "MD" rather than "DM".
+func Decode(word uint16) (Instruction, error) {
+	return decoderTables.decode(word)
+}
+
+// A decoder turns binary Hack instructions back into assembly instructions, using the inverse of
+// the tables HackWriter uses.
+type decoder struct {
+	compTable, destTable, jumpTable map[string]string
+}
+
+func newDecoder() *decoder {
+	return &decoder{
+		compTable: invert(compTable()),
+		destTable: invert(destTable()),
+		jumpTable: invert(jumpTable()),
+	}
+}
+
+func invert(m map[string]string) map[string]string {
+	inverse := make(map[string]string, len(m))
+	for k, v := range m {
+		inverse[v] = k
+	}
+	return inverse
+}
+
+// decode returns a DecimalAInstruction or CInstruction for the given word.
+func (d *decoder) decode(word uint16) (Instruction, error) {
+	if word&0x8000 == 0 {
+		return DecimalAInstruction{Value: uint(word)}, nil
+	}
+	c, err := d.decodeC(word)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (d *decoder) decodeC(word uint16) (CInstruction, error) {
+	bits := fmt.Sprintf("%016b", word)
+	if bits[1:3] != "11" {
+		return CInstruction{}, fmt.Errorf("invalid C-instruction %s: bits 13 and 14 must be 1", bits)
+	}
+	comp, ok := d.compTable[bits[3:10]]
+	if !ok {
+		return CInstruction{}, fmt.Errorf("invalid C-instruction %s: unknown comp field %s", bits, bits[3:10])
+	}
+	return CInstruction{
+		Dest: canonicalDest(d.destTable[bits[10:13]]),
+		Comp: comp,
+		Jump: d.jumpTable[bits[13:16]],
+	}, nil
+}
@@ -0,0 +1,107 @@
+package asm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	binary := `0000000000000000
+1111110000010000
+0000000000000001
+1111010011010000
+0000000000001010
+1110001100000001
+0000000000000001
+1111110000010000
+0000000000001100
+1110101010000111
+0000000000000000
+1111110000010000
+0000000000000010
+1110001100001000
+0000000000001110
+1110101010000111
+`
+	want := `    @0
+    D=M
+    @1
+    D=D-M
+    @L10
+    D;JGT
+    @1
+    D=M
+    @L12
+    0;JMP
+(L10)
+    @0
+    D=M
+(L12)
+    @2
+    M=D
+(L14)
+    @L14
+    0;JMP
+`
+	var output strings.Builder
+	err := Disassemble("test.hack", strings.NewReader(binary), &output, true)
+	if err != nil {
+		t.Fatalf("Disassemble returned error: %v", err)
+	}
+	got := output.String()
+	if got != want {
+		t.Errorf("Disassemble produced:\n%s\nwant:\n%s", got, want)
+	}
+
+	// assembling the output should give the original program
+	var reassembled strings.Builder
+	err = Run("test.asm", strings.NewReader(got), &reassembled, Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if reassembled.String() != binary {
+		t.Errorf("reassembled program:\n%s\ndiffers from original:\n%s", reassembled.String(), binary)
+	}
+}
+
+func TestDisassembleInvalid(t *testing.T) {
+	binary := "1110110000010000\n1010110000010000\n1111111111010000\n"
+	want := "    D=A\n    // invalid instruction: 1010110000010000\n    // invalid instruction: 1111111111010000\n"
+	var output strings.Builder
+	err := Disassemble("test.hack", strings.NewReader(binary), &output, false)
+	var errs ErrorList
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Disassemble returned %v, want two errors", err)
+	}
+	if errs[0].Pos.Line != 2 || errs[1].Pos.Line != 3 {
+		t.Errorf("Disassemble reported errors at %v and %v, want lines 2 and 3", errs[0].Pos, errs[1].Pos)
+	}
+	got := output.String()
+	if got != want {
+		t.Errorf("Disassemble produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDecodeAllComp(t *testing.T) {
+	w := NewHackWriter(nil)
+	d := newDecoder()
+	for comp := range compTable() {
+		for dest := range destTable() {
+			for jump := range jumpTable() {
+				instruction := CInstruction{dest, comp, jump}
+				code, err := w.encodeC(instruction)
+				if err != nil {
+					t.Fatalf("encodeC(%#v) returned error: %v", instruction, err)
+				}
+				got, err := d.decodeC(code)
+				if err != nil {
+					t.Fatalf("decodeC(%016b) returned error: %v", code, err)
+				}
+				if got.Comp != comp || got.Jump != jump || len(got.Dest) != len(dest) {
+					t.Errorf("decodeC(%016b) returned %#v, want %#v", code, got, instruction)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+/*
+Package asm implements the Hack assembler and tools for working with Hack assembly programs.
+
+Parse reads a program into a list of statements, each with its position in the source. The
+statements are typed instructions (SymbolicAInstruction, DecimalAInstruction, CInstruction, and
+LInstruction) and directives (EquDirective, GlobalDirective, and ExternDirective) that all
+implement the Instruction interface. Parse runs the preprocessor first, so includes, macros, and
+pseudo-instructions like "D=@5" are expanded; Parser reads the lines of a single file without
+preprocessing them. Print writes statements back as assembly, FormatSource formats assembly source
+in the canonical style, and Encode and Decode convert between instructions and 16-bit words of
+binary code.
+
+Run and RunFiles run the whole assembler, as the assembler command does. Options selects the
+output Format, an optimization level from OptimizeNone to OptimizeSize, a listing, and how
+warnings are reported. Errors in the program are returned as an ErrorList of Errors with their
+positions. Disassemble goes the other way.
+
+With Options.Object set, Run writes a relocatable Object instead of a program: code with
+addresses starting at 0, the labels it exports with .global, and the symbols it expects from other
+object files with .extern. WriteObject and ReadObject convert an Object to and from its text
+format, and Link combines object files into one program, resolving symbols and allocating
+variables for all of them together.
+
+BuildCFG splits a program into basic blocks and builds its control-flow graph (CFG), which can be
+written in DOT or JSON format, and DeadCode uses the graph to find code that has no effect.
+*/
+package asm
@@ -0,0 +1,96 @@
+package asm
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// A Pos is a position in a Hack assembly file. Line and Column start at 1.
+type Pos struct {
+	File         string
+	Line, Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// An Error is an error in a Hack assembly program, with the position where it occurred.
+type Error struct {
+	Pos    Pos
+	Msg    string
+	Source string // the source line containing the error
+
+	// Related optionally points at a second place in the program that's relevant to the error,
+	// for example the first declaration of a label that's declared twice.
+	Related *Error
+
+	// Warning is set for problems that don't stop the program from being assembled, like a label
+	// that's never used.
+	Warning bool
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: ", e.Pos)
+	if e.Warning {
+		b.WriteString("warning: ")
+	}
+	b.WriteString(e.Msg)
+	if e.Source != "" {
+		fmt.Fprintf(&b, "\n\t%s\n\t%s^", e.Source, caretIndent(e.Source, e.Pos.Column))
+	}
+	if e.Related != nil {
+		fmt.Fprintf(&b, "\n%v", e.Related)
+	}
+	return b.String()
+}
+
+// caretIndent returns the whitespace that goes before a caret pointing at the given column of a
+// source line. Tabs are kept so the caret lines up no matter how wide the terminal displays them.
+func caretIndent(source string, column int) string {
+	var b strings.Builder
+	for i, c := range source {
+		if i >= column-1 {
+			break
+		}
+		if c == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
+
+// An ErrorList is a list of errors found in a Hack assembly program.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, e := range l {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Sort sorts the list by position.
+func (l ErrorList) Sort() {
+	slices.SortStableFunc(l, func(a, b *Error) int {
+		return cmp.Or(
+			cmp.Compare(a.Pos.File, b.Pos.File),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+		)
+	})
+}
+
+// Err returns the list as an error, or nil if it's empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
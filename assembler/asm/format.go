@@ -0,0 +1,152 @@
+package asm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is an enum for the file formats the assembler can write binary Hack programs in.
+type Format int
+
+const (
+	// FormatHack is the text format used by the course tools: one instruction per line, written
+	// as 16 '0' and '1' characters.
+	FormatHack Format = iota
+
+	// FormatRaw is raw binary: each instruction as a 16-bit big-endian word.
+	FormatRaw
+
+	// FormatIntelHex is Intel HEX with one data record per instruction. Addresses count 16-bit
+	// words rather than bytes, which is what Quartus expects for memories with 16-bit words.
+	FormatIntelHex
+
+	// FormatReadmemb is a memory file for Verilog's $readmemb: one binary number per line.
+	FormatReadmemb
+
+	// FormatReadmemh is a memory file for Verilog's $readmemh: one hexadecimal number per line.
+	FormatReadmemh
+
+	// FormatLogisim is a Logisim memory image that can be loaded into a ROM component.
+	FormatLogisim
+)
+
+var formatNames = []string{
+	FormatHack:     "hack",
+	FormatRaw:      "raw",
+	FormatIntelHex: "ihex",
+	FormatReadmemb: "readmemb",
+	FormatReadmemh: "readmemh",
+	FormatLogisim:  "logisim",
+}
+
+var formatExtensions = []string{
+	FormatHack:     ".hack",
+	FormatRaw:      ".bin",
+	FormatIntelHex: ".hex",
+	FormatReadmemb: ".mem",
+	FormatReadmemh: ".mem",
+	FormatLogisim:  ".img",
+}
+
+func (f Format) String() string {
+	if f >= 0 && int(f) < len(formatNames) {
+		return formatNames[f]
+	}
+	return fmt.Sprintf("<format %d>", f)
+}
+
+// Extension returns the usual file extension for the format, including the dot, or "" for an
+// unknown format.
+func (f Format) Extension() string {
+	if f >= 0 && int(f) < len(formatExtensions) {
+		return formatExtensions[f]
+	}
+	return ""
+}
+
+// ParseFormat returns the format with the given name, as returned by Format.String.
+func ParseFormat(name string) (Format, error) {
+	for f, n := range formatNames {
+		if n == name {
+			return Format(f), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown output format %q (expected one of: %s)", name, strings.Join(formatNames, ", "))
+}
+
+// writeProgram writes a binary Hack program to w in the given format.
+func writeProgram(w io.Writer, program []uint16, format Format) error {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatHack:
+		hackWriter := NewHackWriter(bw)
+		for _, code := range program {
+			if err := hackWriter.write(code); err != nil {
+				return err
+			}
+		}
+	case FormatRaw:
+		if err := binary.Write(bw, binary.BigEndian, program); err != nil {
+			return err
+		}
+	case FormatIntelHex:
+		for address, code := range program {
+			record := []byte{2, byte(address >> 8), byte(address), 0, byte(code >> 8), byte(code)}
+			writeHexRecord(bw, record)
+		}
+		writeHexRecord(bw, []byte{0, 0, 0, 1})
+	case FormatReadmemb:
+		fmt.Fprintf(bw, "// Hack program, %d instructions\n", len(program))
+		for _, code := range program {
+			fmt.Fprintf(bw, "%016b\n", code)
+		}
+	case FormatReadmemh:
+		fmt.Fprintf(bw, "// Hack program, %d instructions\n", len(program))
+		for _, code := range program {
+			fmt.Fprintf(bw, "%04x\n", code)
+		}
+	case FormatLogisim:
+		fmt.Fprintln(bw, "v2.0 raw")
+		for i, code := range program {
+			separator := " "
+			if i%8 == 7 || i == len(program)-1 {
+				separator = "\n"
+			}
+			fmt.Fprintf(bw, "%x%s", code, separator)
+		}
+	default:
+		return fmt.Errorf("unknown output format: %v", format)
+	}
+	return bw.Flush()
+}
+
+// writeHexRecord writes an Intel HEX record, given its length, address, type, and data bytes.
+// It adds the start code and checksum.
+func writeHexRecord(w io.Writer, record []byte) {
+	var sum byte
+	fmt.Fprint(w, ":")
+	for _, b := range record {
+		fmt.Fprintf(w, "%02X", b)
+		sum += b
+	}
+	fmt.Fprintf(w, "%02X\n", -sum)
+}
+
+// readRaw reads a binary Hack program in the raw format: 16-bit big-endian words.
+func readRaw(r io.Reader) ([]uint16, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("raw program has odd length (%d bytes)", len(data))
+	}
+	program := make([]uint16, len(data)/2)
+	for i := range program {
+		program[i] = binary.BigEndian.Uint16(data[2*i:])
+	}
+	return program, nil
+}
@@ -0,0 +1,78 @@
+package asm
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteProgram(t *testing.T) {
+	// @2, D=A, @0, M=D
+	program := []uint16{0x0002, 0xec10, 0x0000, 0xe308}
+	cases := []struct {
+		format Format
+		want   string
+	}{
+		{FormatHack, "0000000000000010\n1110110000010000\n0000000000000000\n1110001100001000\n"},
+		{FormatRaw, "\x00\x02\xec\x10\x00\x00\xe3\x08"},
+		{FormatIntelHex, ":020000000002FC\n:02000100EC1001\n:020002000000FC\n:02000300E30810\n:00000001FF\n"},
+		{FormatReadmemb, "// Hack program, 4 instructions\n0000000000000010\n1110110000010000\n0000000000000000\n1110001100001000\n"},
+		{FormatReadmemh, "// Hack program, 4 instructions\n0002\nec10\n0000\ne308\n"},
+		{FormatLogisim, "v2.0 raw\n2 ec10 0 e308\n"},
+	}
+	for _, c := range cases {
+		var output bytes.Buffer
+		err := writeProgram(&output, program, c.format)
+		if err != nil {
+			t.Errorf("writeProgram for format %v returned error: %v", c.format, err)
+			continue
+		}
+		got := output.String()
+		if got != c.want {
+			t.Errorf("writeProgram for format %v produced:\n%q\nwant:\n%q", c.format, got, c.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range formatNames {
+		f, err := ParseFormat(name)
+		if err != nil {
+			t.Errorf("ParseFormat(%q) returned error: %v", name, err)
+			continue
+		}
+		if f.String() != name {
+			t.Errorf("ParseFormat(%q) returned %v", name, f)
+		}
+	}
+	if _, err := ParseFormat("elf"); err == nil {
+		t.Errorf("ParseFormat(%q) did not return error", "elf")
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	for _, f := range []Format{-1, Format(len(formatNames))} {
+		if got, want := f.String(), fmt.Sprintf("<format %d>", f); got != want {
+			t.Errorf("String for format %d returned %q, want %q", int(f), got, want)
+		}
+		if got := f.Extension(); got != "" {
+			t.Errorf("Extension for format %d returned %q, want \"\"", int(f), got)
+		}
+	}
+}
+
+func TestReadRaw(t *testing.T) {
+	got, err := readRaw(strings.NewReader("\x00\x02\xec\x10"))
+	if err != nil {
+		t.Fatalf("readRaw returned error: %v", err)
+	}
+	want := []uint16{0x0002, 0xec10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readRaw returned %#v, want %#v", got, want)
+	}
+	if _, err := readRaw(strings.NewReader("\x00\x02\xec")); err == nil {
+		t.Error("readRaw did not return error for odd length")
+	}
+}
@@ -1,9 +1,10 @@
-package internal
+package asm
 
 import (
 	"fmt"
 	"io"
 	"slices"
+	"strconv"
 )
 
 // HackWriter writes binary instructions for Hack programs.
@@ -24,33 +25,75 @@ func NewHackWriter(w io.Writer) *HackWriter {
 
 // AInstruction writes an A-instruction.
 func (w *HackWriter) AInstruction(i DecimalAInstruction) error {
-	_, err := fmt.Fprintf(w.w, "0%015b\n", i.Value)
+	return w.write(encodeA(i))
+}
+
+// CInstruction writes a C-instruction.
+func (w *HackWriter) CInstruction(i CInstruction) error {
+	code, err := w.encodeC(i)
 	if err != nil {
 		return err
 	}
-	return nil
+	return w.write(code)
 }
 
-// CInstruction writes a C-instruction.
-func (w *HackWriter) CInstruction(i CInstruction) error {
+func (w *HackWriter) write(code uint16) error {
+	_, err := fmt.Fprintf(w.w, "%016b\n", code)
+	return err
+}
+
+// encoder is used by Encode.
+var encoder = NewHackWriter(nil)
+
+// Encode returns the binary code for an A- or C-instruction. Symbolic A-instructions have to be
+// resolved to DecimalAInstructions first; labels and .equ directives don't produce code.
+func Encode(i Instruction) (uint16, error) {
+	switch i := i.(type) {
+	case DecimalAInstruction:
+		if i.Value > maxValue {
+			return 0, fmt.Errorf("value out of range in A-instruction: %d (maximum is %d)", i.Value, maxValue)
+		}
+		return encodeA(i), nil
+	case CInstruction:
+		return encoder.encodeC(i)
+	}
+	return 0, fmt.Errorf("can't encode %v: only decimal A-instructions and C-instructions have binary code", i)
+}
+
+// encodeA returns the binary code for an A-instruction.
+func encodeA(i DecimalAInstruction) uint16 {
+	return uint16(i.Value)
+}
+
+// encodeC returns the binary code for a C-instruction.
+func (w *HackWriter) encodeC(i CInstruction) (uint16, error) {
 	comp, ok := w.compTable[i.Comp]
 	if !ok {
-		return fmt.Errorf("invalid comp field in C-instruction: %q", i.Comp)
+		return 0, &FieldError{"comp", i.Comp}
 	}
 	dest, ok := w.destCode(i.Dest)
 	if !ok {
-		return fmt.Errorf("invalid dest field in C-instruction: %q", i.Dest)
+		return 0, &FieldError{"dest", i.Dest}
 	}
 	jump, ok := w.jumpTable[i.Jump]
 	if !ok {
-		return fmt.Errorf("invalid jump field in C-instruction: %q", i.Jump)
+		return 0, &FieldError{"jump", i.Jump}
 	}
-
-	_, err := fmt.Fprintf(w.w, "111%s%s%s\n", comp, dest, jump)
+	code, err := strconv.ParseUint("111"+comp+dest+jump, 2, 16)
 	if err != nil {
-		return err
+		panic(err) // can't happen, the tables contain only valid binary numbers
 	}
-	return nil
+	return uint16(code), nil
+}
+
+// A FieldError is returned by HackWriter.CInstruction if one of the fields of the C-instruction is
+// invalid.
+type FieldError struct {
+	Field, Value string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid %s field in C-instruction: %q", e.Field, e.Value)
 }
 
 func (w *HackWriter) destCode(value string) (string, bool) {
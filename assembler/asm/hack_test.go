@@ -1,4 +1,4 @@
-package internal
+package asm
 
 import (
 	"strings"
@@ -52,3 +52,39 @@ func TestWriteCInstruction(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeDecode(t *testing.T) {
+	cases := []struct {
+		instruction Instruction
+		code        uint16
+	}{
+		{DecimalAInstruction{Value: 21}, 0b0000000000010101},
+		{CInstruction{Dest: "MD", Comp: "D+1", Jump: "JGT"}, 0b1110011111011001},
+		{CInstruction{Comp: "0", Jump: "JMP"}, 0b1110101010000111},
+	}
+	for _, c := range cases {
+		code, err := Encode(c.instruction)
+		if err != nil {
+			t.Errorf("Encode(%v) returned error: %v", c.instruction, err)
+		} else if code != c.code {
+			t.Errorf("Encode(%v) returned %016b, want %016b", c.instruction, code, c.code)
+		}
+		instruction, err := Decode(c.code)
+		if err != nil {
+			t.Errorf("Decode(%016b) returned error: %v", c.code, err)
+		} else if instruction != c.instruction {
+			t.Errorf("Decode(%016b) returned %v, want %v", c.code, instruction, c.instruction)
+		}
+	}
+
+	for _, i := range []Instruction{
+		SymbolicAInstruction{Symbol: "LOOP"},
+		LInstruction{Symbol: "LOOP"},
+		DecimalAInstruction{Value: 40000},
+		CInstruction{Comp: "X"},
+	} {
+		if _, err := Encode(i); err == nil {
+			t.Errorf("Encode(%v) did not return error", i)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InstructionType is an enum for the different types of instructions in Hack assembly programs.
+type InstructionType int
+
+const (
+	TypeADecimal InstructionType = iota
+	TypeASymbolic
+	TypeC
+	TypeL
+	TypeEqu
+	TypeGlobal
+	TypeExtern
+)
+
+// maxValue is the largest value an A-instruction can hold.
+const maxValue = 1<<15 - 1
+
+// An Instruction is an element of a Hack assembly program: a SymbolicAInstruction,
+// DecimalAInstruction, CInstruction, LInstruction, EquDirective, GlobalDirective, or
+// ExternDirective. The String method returns the instruction as it's written in assembly.
+type Instruction interface {
+	String() string
+	instruction()
+}
+
+// A SymbolicAInstruction is an A-instruction that contains a symbol, for example "@START".
+type SymbolicAInstruction struct {
+	Symbol string
+}
+
+// A DecimalAInstruction is an A-instruction that contains a concrete number, for example "@20",
+// "@0x4000", "@0b101", or "@'A'".
+type DecimalAInstruction struct {
+	Value uint
+}
+
+// A CInstruction represents a C-instruction with dest, comp, and jump parts, for example
+// "A=D;JGT". Dest and Jump may be empty strings.
+type CInstruction struct {
+	Dest, Comp, Jump string
+}
+
+// offset returns the offset of the named field ("dest", "comp", or "jump") from the start of the
+// instruction as written in the source.
+func (i CInstruction) offset(field string) int {
+	compOffset := 0
+	if i.Dest != "" {
+		compOffset = len(i.Dest) + 1
+	}
+	switch field {
+	case "comp":
+		return compOffset
+	case "jump":
+		return compOffset + len(i.Comp) + 1
+	}
+	return 0
+}
+
+// An LInstruction represents a label pseudo-instruction, for example "(START)".
+type LInstruction struct {
+	Symbol string
+}
+
+// An EquDirective defines a symbolic constant, for example ".equ WIDTH 32". Value is either a
+// number or a symbol defined earlier in the program.
+type EquDirective struct {
+	Symbol, Value string
+}
+
+func (i SymbolicAInstruction) String() string { return "@" + i.Symbol }
+func (i DecimalAInstruction) String() string  { return fmt.Sprintf("@%d", i.Value) }
+func (i LInstruction) String() string         { return "(" + i.Symbol + ")" }
+func (d EquDirective) String() string         { return ".equ " + d.Symbol + " " + d.Value }
+
+func (i CInstruction) String() string {
+	var b strings.Builder
+	if i.Dest != "" {
+		b.WriteString(i.Dest)
+		b.WriteString("=")
+	}
+	b.WriteString(i.Comp)
+	if i.Jump != "" {
+		b.WriteString(";")
+		b.WriteString(i.Jump)
+	}
+	return b.String()
+}
+
+// A GlobalDirective exports a label from an object file, for example ".global Math.multiply", so
+// other object files can use it.
+type GlobalDirective struct {
+	Symbol string
+}
+
+// An ExternDirective declares a label that's defined in another object file, for example
+// ".extern Math.multiply". The linker reports an error if no object file exports it; when the
+// assembler builds a program directly, the symbol must be declared in the program instead.
+type ExternDirective struct {
+	Symbol string
+}
+
+func (d GlobalDirective) String() string { return ".global " + d.Symbol }
+func (d ExternDirective) String() string { return ".extern " + d.Symbol }
+
+func (SymbolicAInstruction) instruction() {}
+func (DecimalAInstruction) instruction()  {}
+func (CInstruction) instruction()         {}
+func (LInstruction) instruction()         {}
+func (EquDirective) instruction()         {}
+func (GlobalDirective) instruction()      {}
+func (ExternDirective) instruction()      {}
@@ -0,0 +1,102 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A tokenKind is the kind of a token in a line of Hack assembly.
+type tokenKind int
+
+const (
+	// tokenWord is a symbol, mnemonic, or number, like "LOOP", "AMD", "JGT", "17", or "0x1f".
+	tokenWord tokenKind = iota
+
+	// tokenChar is a character literal in single quotes, like 'A'.
+	tokenChar
+
+	// tokenPunct is one of the characters @ ( ) = ; + - ! & |.
+	tokenPunct
+)
+
+// A token is a word, character literal, or punctuation character in a line of Hack assembly.
+type token struct {
+	kind   tokenKind
+	text   string
+	column int // column where the token starts, starting at 1
+}
+
+// A lexError is an error found by the lexer, with the column where it occurred.
+type lexError struct {
+	column int
+	msg    string
+}
+
+func (e *lexError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.column, e.msg)
+}
+
+// lex splits a line of Hack assembly into tokens. Whitespace separates tokens and is otherwise
+// ignored; a comment starting with "//" runs to the end of the line.
+func lex(line string) ([]token, *lexError) {
+	var tokens []token
+	for i := 0; i < len(line); {
+		c, size := utf8.DecodeRuneInString(line[i:])
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i += size
+		case strings.HasPrefix(line[i:], "//"):
+			return tokens, nil
+		case isSymbolChar(c):
+			start := i
+			for i < len(line) {
+				r, size := utf8.DecodeRuneInString(line[i:])
+				if !isSymbolChar(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{tokenWord, line[start:i], start + 1})
+		case c == '\'':
+			start := i
+			for i++; i < len(line) && line[i] != '\''; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return tokens, &lexError{start + 1, "unterminated character literal"}
+			}
+			i++
+			tokens = append(tokens, token{tokenChar, line[start:i], start + 1})
+		case strings.ContainsRune("@()=;+-!&|", c):
+			tokens = append(tokens, token{tokenPunct, string(c), i + 1})
+			i++
+		default:
+			return tokens, &lexError{i + 1, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return tokens, nil
+}
+
+// normalize lexes a line and returns it with comments and whitespace removed, keeping only a single
+// space between adjacent words, for example "D=D+M" for "  D = D + M  // add". Along with the
+// text, it returns the column in the original line of each byte in the text, so errors can point
+// at the right place.
+func normalize(line string) (string, []int, *lexError) {
+	tokens, err := lex(line)
+	var b strings.Builder
+	var columns []int
+	for i, t := range tokens {
+		if i > 0 && t.kind != tokenPunct && tokens[i-1].kind != tokenPunct {
+			b.WriteByte(' ')
+			columns = append(columns, t.column-1)
+		}
+		b.WriteString(t.text)
+		for j := range len(t.text) {
+			columns = append(columns, t.column+j)
+		}
+	}
+	return b.String(), columns, err
+}
@@ -0,0 +1,103 @@
+package asm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		line, want string
+	}{
+		{"", ""},
+		{"   // just a comment", ""},
+		{"D=M // load", "D=M"},
+		{"  D = M ; JGT", "D=M;JGT"},
+		{"\tAM\t=\tM + 1", "AM=M+1"},
+		{"@ LOOP", "@LOOP"},
+		{"( LOOP )//label", "(LOOP)"},
+		{"@'/' // slash", "@'/'"},
+		{".equ N  3", ".equ N 3"},
+		{"D=D X", "D=D X"},
+	}
+	for _, c := range cases {
+		got, _, err := normalize(c.line)
+		if err != nil {
+			t.Errorf("normalize(%q) returned error: %v", c.line, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("normalize(%q) returned %q, want %q", c.line, got, c.want)
+		}
+	}
+}
+
+func TestNormalizeColumns(t *testing.T) {
+	_, columns, err := normalize("  D = M")
+	if err != nil {
+		t.Fatalf("normalize returned error: %v", err)
+	}
+	want := []int{3, 5, 7}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("normalize returned columns %v, want %v", columns, want)
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	cases := []struct {
+		line   string
+		column int
+		msg    string
+	}{
+		{"D=M#1", 4, "unexpected character '#'"},
+		{"  D=M / 2", 7, "unexpected character '/'"},
+		{"@'A", 2, "unterminated character literal"},
+		{"\tD=M€", 5, "unexpected character '€'"},
+	}
+	for _, c := range cases {
+		_, err := lex(c.line)
+		if err == nil {
+			t.Errorf("lex(%q) did not return error", c.line)
+			continue
+		}
+		if err.column != c.column || err.msg != c.msg {
+			t.Errorf("lex(%q) returned error at column %d: %s, want column %d: %s",
+				c.line, err.column, err.msg, c.column, c.msg)
+		}
+	}
+}
+
+func TestRunInlineComments(t *testing.T) {
+	source := "(LOOP) // start\n  @ LOOP\n  D = M + 1 ; JGT // loop\n  M=D#\n  DX = M\n"
+	var output strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{})
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Run returned %v, want an ErrorList", err)
+	}
+	want := []string{
+		"test.asm:4:6: unexpected character '#'",
+		"test.asm:5:3: invalid dest field in C-instruction: \"DX\"",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Run returned %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, e := range errs {
+		got := e.Pos.String() + ": " + e.Msg
+		if got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+
+	source = "(LOOP) // start\n  @ LOOP\n  D = M + 1 ; JGT // loop\n"
+	output.Reset()
+	if err := Run("test.asm", strings.NewReader(source), &output, Options{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	wantOutput := "0000000000000000\n1111110111010001\n"
+	if got := output.String(); got != wantOutput {
+		t.Errorf("Run produced:\n%s\nwant:\n%s", got, wantOutput)
+	}
+}
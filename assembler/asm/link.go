@@ -0,0 +1,95 @@
+package asm
+
+import (
+	"fmt"
+	"io"
+)
+
+// Link combines object files into a binary program and writes it to w in the given format.
+// Object files are placed in ROM in the order given. Labels exported with .global can be used in
+// all object files; other symbols that aren't defined in the object file using them are variables,
+// allocated starting at address 16 in the order they're first used.
+//
+// Errors, like symbols exported by more than one object file, .extern symbols that no object file
+// exports, or addresses that don't fit in an A-instruction, are returned as an ErrorList.
+func Link(objects []*Object, w io.Writer, format Format) error {
+	var errs ErrorList
+
+	// place the object files and collect the exported labels
+	bases := make([]uint, len(objects))
+	globals := make(map[string]Symbol)
+	var size uint
+	for i, o := range objects {
+		bases[i] = size
+		for _, g := range o.Globals {
+			if previous, ok := globals[g.Name]; ok {
+				errs = append(errs, &Error{
+					Pos:     g.Pos,
+					Msg:     fmt.Sprintf("symbol %q exported twice", g.Name),
+					Related: &Error{Pos: previous.Pos, Msg: fmt.Sprintf("first export of %q", g.Name)},
+				})
+				continue
+			}
+			g.Address += size
+			globals[g.Name] = g
+		}
+		size += uint(len(o.Code))
+	}
+	if size > romSize {
+		errs = append(errs, &Error{
+			Pos: Pos{File: objects[len(objects)-1].Source, Line: 1, Column: 1},
+			Msg: fmt.Sprintf("program too large: %d instructions, ROM holds %d", size, romSize),
+		})
+	}
+
+	// resolve references
+	variables := make(map[string]uint)
+	nextAddress := uint(16)
+	var program []uint16
+	for i, o := range objects {
+		code := make([]uint16, len(o.Code))
+		copy(code, o.Code)
+		for _, r := range o.Relocations {
+			address := uint(code[r.Index]) + bases[i]
+			if address > maxValue {
+				errs = append(errs, &Error{Pos: r.Pos, Msg: fmt.Sprintf("address of %q doesn't fit in an A-instruction: %d", r.Symbol, address)})
+				continue
+			}
+			code[r.Index] = uint16(address)
+		}
+		externs := make(map[string]bool)
+		for _, e := range o.Externs {
+			externs[e.Name] = true
+			if _, ok := globals[e.Name]; !ok {
+				errs = append(errs, &Error{Pos: e.Pos, Msg: fmt.Sprintf("undefined symbol %q", e.Name)})
+			}
+		}
+		for _, r := range o.References {
+			var value uint
+			if g, ok := globals[r.Symbol]; ok {
+				value = g.Address
+			} else if externs[r.Symbol] {
+				// already reported
+				continue
+			} else if address, ok := variables[r.Symbol]; ok {
+				value = address
+			} else {
+				value = nextAddress
+				variables[r.Symbol] = value
+				nextAddress++
+			}
+			if value > maxValue {
+				errs = append(errs, &Error{Pos: r.Pos, Msg: fmt.Sprintf("address of %q doesn't fit in an A-instruction: %d", r.Symbol, value)})
+				continue
+			}
+			code[r.Index] = uint16(value)
+		}
+		program = append(program, code...)
+	}
+
+	if len(errs) > 0 {
+		errs.Sort()
+		return errs
+	}
+	return writeProgram(w, program, format)
+}
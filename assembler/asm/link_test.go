@@ -0,0 +1,192 @@
+package asm
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// objectFor assembles source into an object file, using WriteObject and ReadObject on the way.
+func objectFor(t *testing.T, filename, source string) *Object {
+	t.Helper()
+	var b strings.Builder
+	err := Run(filename, strings.NewReader(source), &b, Options{Object: true})
+	if err != nil {
+		t.Fatalf("Run for %s returned error: %v", filename, err)
+	}
+	o, err := ReadObject(filename+".obj", strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("ReadObject for %s returned error: %v", filename, err)
+	}
+	return o
+}
+
+func TestObject(t *testing.T) {
+	source := `.extern inc
+.global main
+.equ N 3
+(main)
+    @N
+    D=A
+    @counter
+    M=D
+(loop)
+    @inc
+    0;JMP
+    @loop
+    0;JMP
+`
+	var b strings.Builder
+	err := Run("main.asm", strings.NewReader(source), &b, Options{Object: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := `HACKOBJ 1
+source main.asm
+global main 0 "main.asm":2:1
+extern inc "main.asm":1:1
+code 0000000000000011
+code 1110110000010000
+code 0000000000000000
+code 1110001100001000
+code 0000000000000000
+code 1110101010000111
+code 0000000000000100
+code 1110101010000111
+reloc 6 loop "main.asm":12:6
+ref 2 counter "main.asm":7:6
+ref 4 inc "main.asm":10:6
+`
+	if got := b.String(); got != want {
+		t.Errorf("Run wrote object file:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLink(t *testing.T) {
+	main := objectFor(t, "main.asm", `.extern inc
+    @counter
+    M=0
+(loop)
+    @inc
+    0;JMP
+.global back
+(back)
+    @loop
+    0;JMP
+`)
+	lib := objectFor(t, "lib.asm", `.global inc
+.extern back
+(inc)
+    @other
+    M=0
+    @counter
+    M=M+1
+(done)
+    @back
+    0;JMP
+`)
+	var b strings.Builder
+	if err := Link([]*Object{main, lib}, &b, FormatHack); err != nil {
+		t.Fatalf("Link returned error: %v", err)
+	}
+	want := `0000000000010000
+1110101010001000
+0000000000000110
+1110101010000111
+0000000000000010
+1110101010000111
+0000000000010001
+1110101010001000
+0000000000010000
+1111110111001000
+0000000000000100
+1110101010000111
+`
+	if got := b.String(); got != want {
+		t.Errorf("Link produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	a := objectFor(t, "a.asm", ".global f\n.extern g\n(f)\n@g\n0;JMP\n")
+	b := objectFor(t, "b.asm", ".global f\n(f)\n@f\n0;JMP\n")
+	err := Link([]*Object{a, b}, io.Discard, FormatHack)
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Link returned %v, want an ErrorList", err)
+	}
+	want := []string{
+		"a.asm:2:1: undefined symbol \"g\"",
+		"b.asm:1:1: symbol \"f\" exported twice\na.asm:1:1: first export of \"f\"",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Link returned %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, e := range errs {
+		if e.Error() != want[i] {
+			t.Errorf("error %d is:\n%s\nwant:\n%s", i, e.Error(), want[i])
+		}
+	}
+}
+
+func TestObjectErrors(t *testing.T) {
+	source := ".global nolabel\n.extern here\n(here)\n@here\n"
+	err := Run("test.asm", strings.NewReader(source), io.Discard, Options{Object: true})
+	var errs ErrorList
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Run returned %v, want two errors", err)
+	}
+	if got := errs[0].Msg; got != "global symbol \"nolabel\" isn't a label in this file" {
+		t.Errorf("error 0 is %q", got)
+	}
+	if got := errs[1].Msg; got != "extern symbol \"here\" is defined in this file" {
+		t.Errorf("error 1 is %q", got)
+	}
+}
+
+func TestLinkAddressRange(t *testing.T) {
+	// the two object files fill the ROM, so the label at the end of b is at 32768
+	big := &Object{Source: "big.asm", Code: make([]uint16, romSize-2)}
+	b := objectFor(t, "b.asm", "@end\n0;JMP\n(end)\n")
+	err := Link([]*Object{big, b}, io.Discard, FormatHack)
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Link returned %v, want an ErrorList", err)
+	}
+	want := "b.asm:1:2: address of \"end\" doesn't fit in an A-instruction: 32768"
+	if len(errs) != 1 || errs[0].Error() != want {
+		t.Fatalf("Link returned:\n%v\nwant:\n%s", err, want)
+	}
+}
+
+func TestReadObjectFileNameWithSpaces(t *testing.T) {
+	o := objectFor(t, "my prog.asm", ".global main\n(main)\n@main\n0;JMP\n@x\n")
+	want := Pos{File: "my prog.asm", Line: 1, Column: 1}
+	if len(o.Globals) != 1 || o.Globals[0].Pos != want {
+		t.Errorf("ReadObject returned globals %v, want main at %v", o.Globals, want)
+	}
+	if len(o.Relocations) != 1 || o.Relocations[0].Pos.File != "my prog.asm" {
+		t.Errorf("ReadObject returned relocations %v", o.Relocations)
+	}
+	if len(o.References) != 1 || o.References[0].Pos.File != "my prog.asm" {
+		t.Errorf("ReadObject returned references %v", o.References)
+	}
+}
+
+func TestReadObjectErrors(t *testing.T) {
+	cases := []struct {
+		line, want string
+	}{
+		{`global main 32768 "a.asm":1:1`, "a.obj:2: invalid object file: address out of range: 32768"},
+		{`global main 0 a.asm:1:1`, "a.obj:2: invalid object file: \"global main 0 a.asm:1:1\""},
+		{`reloc 0 main "a.asm":1`, "a.obj:2: invalid object file: \"reloc 0 main \\\"a.asm\\\":1\""},
+		{`ref 5 x "a.asm":1:1`, "a.obj: invalid object file: index 5 out of range"},
+	}
+	for _, c := range cases {
+		_, err := ReadObject("a.obj", strings.NewReader("HACKOBJ 1\n"+c.line+"\ncode 0000000000000000\n"))
+		if err == nil || err.Error() != c.want {
+			t.Errorf("ReadObject for %q returned %v, want %s", c.line, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// A listing collects the information for a listing file: the ROM address and binary code of each
+// line of the source program, and the kind of each symbol.
+type listing struct {
+	lines     map[int]listingLine // by index in the program's lines, starting at 1
+	variables map[string]bool
+	constants map[string]bool
+}
+
+type listingLine struct {
+	address uint
+	code    uint16
+	label   bool
+}
+
+func newListing() *listing {
+	return &listing{
+		lines:     make(map[int]listingLine),
+		variables: make(map[string]bool),
+		constants: make(map[string]bool),
+	}
+}
+
+// instruction records the address and code of the instruction on the line with the given index.
+// Like the other methods, it does nothing if l is nil.
+func (l *listing) instruction(line int, address uint, code uint16) {
+	if l == nil {
+		return
+	}
+	l.lines[line] = listingLine{address: address, code: code}
+}
+
+// label records the address of the label declared on the line with the given index.
+func (l *listing) label(line int, address uint) {
+	if l == nil {
+		return
+	}
+	l.lines[line] = listingLine{address: address, label: true}
+}
+
+// variable records that a symbol was allocated as a variable.
+func (l *listing) variable(symbol string) {
+	if l == nil {
+		return
+	}
+	l.variables[symbol] = true
+}
+
+// constant records that a symbol was defined as a constant with .equ.
+func (l *listing) constant(symbol string) {
+	if l == nil {
+		return
+	}
+	l.constants[symbol] = true
+}
+
+// write writes the listing to w: each line of the program with its address and code, then the
+// symbol table sorted by name. Lines produced by expanding a macro are marked with a '+'.
+func (l *listing) write(w io.Writer, lines []sourceLine, symbolTable map[string]uint) error {
+	bw := bufio.NewWriter(w)
+	for i, source := range lines {
+		marker := " "
+		if source.call != nil {
+			marker = "+"
+		}
+		line, ok := l.lines[i+1]
+		switch {
+		case !ok:
+			fmt.Fprintf(bw, "%5s  %16s %s%s\n", "", "", marker, source.text)
+		case line.label:
+			fmt.Fprintf(bw, "%5d  %16s %s%s\n", line.address, "", marker, source.text)
+		default:
+			fmt.Fprintf(bw, "%5d  %016b %s%s\n", line.address, line.code, marker, source.text)
+		}
+	}
+
+	fmt.Fprintf(bw, "\nSymbol table:\n")
+	predefined := predefinedSymbols()
+	var symbols []string
+	for symbol := range symbolTable {
+		symbols = append(symbols, symbol)
+	}
+	slices.Sort(symbols)
+	for _, symbol := range symbols {
+		kind := "label"
+		if _, ok := predefined[symbol]; ok {
+			kind = "predefined"
+		} else if l.variables[symbol] {
+			kind = "variable"
+		} else if l.constants[symbol] {
+			kind = "constant"
+		}
+		fmt.Fprintf(bw, "%5d  %-10s  %s\n", symbolTable[symbol], kind, symbol)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,45 @@
+package asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListing(t *testing.T) {
+	source := `// count down
+(loop)
+  @i
+  M=M-1
+  @loop
+  0;JMP
+`
+	var output, listing strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{Listing: &listing})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := `                         // count down
+    0                    (loop)
+    0  0000000000010000    @i
+    1  1111110010001000    M=M-1
+    2  0000000000000000    @loop
+    3  1110101010000111    0;JMP
+
+Symbol table:
+    2  predefined  ARG
+24576  predefined  KBD
+    1  predefined  LCL
+`
+	got := listing.String()
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("listing starts with:\n%s\nwant:\n%s", got[:min(len(got), len(want))], want)
+	}
+	for _, line := range []string{
+		"\n    0  label       loop\n",
+		"\n   16  variable    i\n",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("listing does not contain %q:\n%s", line, got)
+		}
+	}
+}
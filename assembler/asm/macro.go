@@ -0,0 +1,174 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxMacroDepth limits how deeply macro calls can be nested, to catch macros that call themselves.
+const maxMacroDepth = 20
+
+// A macro is a sequence of lines defined with .macro and .endm that can be inserted into a program
+// by calling the macro by name, for example:
+//
+//	.macro PUSHC value
+//	    @value
+//	    D=A
+//	    @SP
+//	    AM=M+1
+//	    A=A-1
+//	    M=D
+//	.endm
+//
+//	    PUSHC 17
+//
+// When the macro is expanded, each parameter is replaced by the corresponding argument. Labels
+// declared in the macro body are local to the expansion: they're renamed so that each expansion
+// gets its own copy.
+type macro struct {
+	name   string
+	params []string
+	body   []sourceLine
+	labels map[string]bool // labels declared in the body
+	def    *sourceLine
+}
+
+// define reads a macro definition from the start of lines and returns the number of lines it
+// takes up, including the .macro and .endm lines.
+func (pp *preprocessor) define(lines []sourceLine, args []string) int {
+	def := &lines[0]
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		name, _ := splitDirective(lines[i].text)
+		if name == ".endm" {
+			end = i
+			break
+		}
+		if name == ".macro" {
+			pp.errorf(&lines[i], "macro definitions can't be nested")
+		}
+	}
+	if end < 0 {
+		pp.errorf(def, "missing .endm for macro definition")
+		return len(lines)
+	}
+
+	if len(args) == 0 {
+		pp.errorf(def, "missing macro name")
+		return end + 1
+	}
+	m := &macro{
+		name:   args[0],
+		params: args[1:],
+		body:   lines[1:end],
+		labels: make(map[string]bool),
+		def:    def,
+	}
+	if !validSymbol(m.name) {
+		pp.errorf(def, "invalid macro name: %q", m.name)
+		return end + 1
+	}
+	if previous, ok := pp.macros[m.name]; ok {
+		e := def.errorf(def.indent()+1, "macro %s defined twice", m.name)
+		e.Related = previous.def.errorf(previous.def.indent()+1, "first definition of %s", m.name)
+		pp.errs = append(pp.errs, e)
+		return end + 1
+	}
+	for _, param := range m.params {
+		if !validSymbol(param) {
+			pp.errorf(def, "invalid macro parameter: %q", param)
+			return end + 1
+		}
+	}
+	for _, l := range m.body {
+		text, _, _ := normalize(l.text)
+		if instructionType(text) == TypeL {
+			if label, err := parseLInstruction(text); err == nil {
+				m.labels[label.Symbol] = true
+			}
+		}
+	}
+	pp.macros[m.name] = m
+	return end + 1
+}
+
+// expand returns the lines produced by calling macro m with the given arguments on line call.
+func (pp *preprocessor) expand(call *sourceLine, m *macro, args []string, depth int) []sourceLine {
+	if depth > maxMacroDepth {
+		pp.errorf(call, "macro calls nested too deeply (recursive macro %s?)", m.name)
+		return nil
+	}
+	if len(args) != len(m.params) {
+		pp.errorf(call, "macro %s expects %d arguments, got %d", m.name, len(m.params), len(args))
+		return nil
+	}
+
+	pp.expansions++
+	replacements := make(map[string]string)
+	for label := range m.labels {
+		replacements[label] = fmt.Sprintf("%s.%d$%s", m.name, pp.expansions, label)
+	}
+	for i, param := range m.params {
+		replacements[param] = args[i]
+	}
+
+	var output []sourceLine
+	for _, l := range m.body {
+		expanded := sourceLine{
+			pos:       l.pos,
+			text:      substitute(l.text, replacements),
+			call:      call,
+			expansion: "macro " + m.name,
+		}
+		name, args := splitDirective(expanded.text)
+		if nested, ok := pp.macros[name]; ok {
+			expanded.directive = true
+			output = append(output, expanded)
+			output = append(output, pp.expand(&expanded, nested, args, depth+1)...)
+			continue
+		}
+		if code, ok := pp.pseudo(&expanded); ok {
+			expanded.directive = true
+			output = append(output, expanded)
+			output = append(output, code...)
+			continue
+		}
+		output = append(output, expanded)
+	}
+	return output
+}
+
+// substitute replaces the symbols in a line of code according to replacements. Comments are left
+// alone.
+func substitute(line string, replacements map[string]string) string {
+	code, comment, hasComment := strings.Cut(line, "//")
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := code[start:end]
+		if replacement, ok := replacements[word]; ok {
+			word = replacement
+		}
+		b.WriteString(word)
+		start = -1
+	}
+	for i, c := range code {
+		if isSymbolChar(c) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		b.WriteRune(c)
+	}
+	flush(len(code))
+	if hasComment {
+		b.WriteString("//")
+		b.WriteString(comment)
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+package asm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMacros(t *testing.T) {
+	source := `
+.macro PUSHC value
+    @value
+    D=A
+    @SP
+    AM=M+1
+    A=A-1
+    M=D
+.endm
+
+.macro MAX x, y // writes max(x, y) to y
+    @x
+    D=M
+    @y
+    D=D-M
+    @DONE
+    D;JLE
+    @x
+    D=M
+    @y
+    M=D
+(DONE)
+.endm
+
+    PUSHC 17
+    MAX R0, R1
+    MAX R2, R3
+`
+	want := `
+    @17
+    D=A
+    @SP
+    AM=M+1
+    A=A-1
+    M=D
+    @R0
+    D=M
+    @R1
+    D=D-M
+    @MAX.2$DONE
+    D;JLE
+    @R0
+    D=M
+    @R1
+    M=D
+(MAX.2$DONE)
+    @R2
+    D=M
+    @R3
+    D=D-M
+    @MAX.3$DONE
+    D;JLE
+    @R2
+    D=M
+    @R3
+    M=D
+(MAX.3$DONE)
+`
+	pp := newPreprocessor(nil)
+	lines := pp.file("test.asm", sourceLines(t, source))
+	if err := pp.errs.Err(); err != nil {
+		t.Fatalf("preprocessor returned error: %v", err)
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, l := range lines {
+		if !l.directive && strings.TrimSpace(l.text) != "" {
+			b.WriteString(l.text + "\n")
+		}
+	}
+	got := b.String()
+	if got != want {
+		t.Errorf("preprocess produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMacroAddresses(t *testing.T) {
+	source := `
+.macro INC addr
+    @addr
+    M=M+1
+.endm
+    INC R0
+(LOOP)
+    INC R1
+    @LOOP
+    0;JMP
+`
+	var output strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := `0000000000000000
+1111110111001000
+0000000000000001
+1111110111001000
+0000000000000010
+1110101010000111
+`
+	got := output.String()
+	if got != want {
+		t.Errorf("Run produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	source := `.macro BAD x
+    D=x
+.endm
+    BAD DX
+    BAD
+.endm
+.frob
+.macro BAD
+.endm
+.macro OPEN
+`
+	var output strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{})
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Run returned %v, want an ErrorList", err)
+	}
+	want := []string{
+		"test.asm:2:7: invalid comp field in C-instruction: \"DX\"",
+		"test.asm:5:5: macro BAD expects 1 arguments, got 0",
+		"test.asm:6:1: .endm without .macro",
+		"test.asm:7:1: unknown directive: .frob",
+		"test.asm:8:1: macro BAD defined twice",
+		"test.asm:10:1: missing .endm for macro definition",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Run returned %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, e := range errs {
+		got := e.Pos.String() + ": " + e.Msg
+		if got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+	call := errs[0].Related
+	if call == nil || call.Pos != (Pos{"test.asm", 4, 5}) {
+		t.Errorf("error in macro expansion points at %v, want call at test.asm:4:5", call)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	replacements := map[string]string{"x": "R1", "LOOP": "M.1$LOOP"}
+	cases := []struct {
+		line, want string
+	}{
+		{"  @x", "  @R1"},
+		{"(LOOP)", "(M.1$LOOP)"},
+		{"D=x+1", "D=R1+1"},
+		{"@x.y // x", "@x.y // x"},
+	}
+	for _, c := range cases {
+		got := substitute(c.line, replacements)
+		if got != c.want {
+			t.Errorf("substitute(%q) returned %q, want %q", c.line, got, c.want)
+		}
+	}
+}
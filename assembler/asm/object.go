@@ -0,0 +1,253 @@
+package asm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// An Object is a relocatable object file: a piece of a program that has been assembled on its own,
+// with addresses starting at 0 and references to symbols that the linker resolves.
+//
+// Labels are local to their object file unless they're exported with .global. A symbol that's
+// used but not defined in the object file is either a label exported by another object file or a
+// variable; the linker allocates variables for all object files together, starting at address 16.
+// Symbols declared with .extern must be exported by another object file.
+type Object struct {
+	Source string // name of the source file
+
+	// Code is the binary code. A-instructions that refer to labels in the object file hold the
+	// label's address relative to the start of the object file; A-instructions that refer to
+	// other symbols hold 0.
+	Code []uint16
+
+	// Relocations are the A-instructions that refer to labels in the object file, so the linker
+	// has to add the object file's start address.
+	Relocations []Reference
+
+	// References are the A-instructions that refer to symbols defined elsewhere.
+	References []Reference
+
+	Globals []Symbol // labels exported with .global
+	Externs []Symbol // symbols declared with .extern; Address is unused
+}
+
+// A Reference is an A-instruction in an Object that refers to a symbol.
+type Reference struct {
+	Index  int // index in Code
+	Symbol string
+	Pos    Pos
+}
+
+// A Symbol is a symbol declared in an Object.
+type Symbol struct {
+	Name    string
+	Address uint // relative to the start of the object file
+	Pos     Pos
+}
+
+// translateObject is like translate, but produces an object file. Symbols are resolved as far as
+// possible; the rest are left to the linker.
+func translateObject(statements []Statement, symbolTable map[string]uint) (*Object, error) {
+	object := &Object{}
+	var errs ErrorList
+	labels := make(map[string]bool)
+	for _, s := range statements {
+		if l, ok := s.Instruction.(LInstruction); ok {
+			labels[l.Symbol] = true
+		}
+	}
+
+	for i := range statements {
+		s := &statements[i]
+		switch instruction := s.Instruction.(type) {
+		case SymbolicAInstruction:
+			symbol := instruction.Symbol
+			value, ok := symbolTable[symbol]
+			pos := s.Pos
+			pos.Column = columnAt(s.columns, 1)
+			switch {
+			case labels[symbol]:
+				object.Relocations = append(object.Relocations, Reference{len(object.Code), symbol, pos})
+			case !ok:
+				object.References = append(object.References, Reference{len(object.Code), symbol, pos})
+			}
+			object.Code = append(object.Code, uint16(value))
+		case DecimalAInstruction:
+			object.Code = append(object.Code, encodeA(instruction))
+		case CInstruction:
+			code, err := encoder.encodeC(instruction)
+			var fieldErr *FieldError
+			if errors.As(err, &fieldErr) {
+				errs = append(errs, s.errorf(instruction.offset(fieldErr.Field), "%v", err))
+				continue
+			}
+			object.Code = append(object.Code, code)
+		case GlobalDirective:
+			if !labels[instruction.Symbol] {
+				errs = append(errs, s.errorf(len(".global "), "global symbol %q isn't a label in this file", instruction.Symbol))
+				continue
+			}
+			object.Globals = append(object.Globals, Symbol{instruction.Symbol, symbolTable[instruction.Symbol], s.Pos})
+		case ExternDirective:
+			if _, ok := symbolTable[instruction.Symbol]; ok {
+				errs = append(errs, s.errorf(len(".extern "), "extern symbol %q is defined in this file", instruction.Symbol))
+				continue
+			}
+			object.Externs = append(object.Externs, Symbol{Name: instruction.Symbol, Pos: s.Pos})
+		}
+	}
+	return object, errs.Err()
+}
+
+// ObjectExtension is the usual file extension for object files.
+const ObjectExtension = ".obj"
+
+// objectHeader is the first line of an object file.
+const objectHeader = "HACKOBJ 1"
+
+// WriteObject writes an object file to w. The format is text, one item per line, with file names
+// in positions quoted as Go strings:
+//
+//	HACKOBJ 1
+//	source main.asm
+//	global Main.main 0 "main.asm":3:1
+//	extern Math.multiply "main.asm":1:1
+//	code 0000000000000000
+//	reloc 0 loop "main.asm":9:6
+//	ref 5 counter "main.asm":12:6
+func WriteObject(w io.Writer, o *Object) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, objectHeader)
+	fmt.Fprintf(bw, "source %s\n", o.Source)
+	for _, g := range o.Globals {
+		fmt.Fprintf(bw, "global %s %d %s\n", g.Name, g.Address, formatPos(g.Pos))
+	}
+	for _, e := range o.Externs {
+		fmt.Fprintf(bw, "extern %s %s\n", e.Name, formatPos(e.Pos))
+	}
+	for _, code := range o.Code {
+		fmt.Fprintf(bw, "code %016b\n", code)
+	}
+	for _, r := range o.Relocations {
+		fmt.Fprintf(bw, "reloc %d %s %s\n", r.Index, r.Symbol, formatPos(r.Pos))
+	}
+	for _, r := range o.References {
+		fmt.Fprintf(bw, "ref %d %s %s\n", r.Index, r.Symbol, formatPos(r.Pos))
+	}
+	return bw.Flush()
+}
+
+// ReadObject reads an object file written by WriteObject. Filename is used in error messages.
+func ReadObject(filename string, r io.Reader) (*Object, error) {
+	o := &Object{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	errorf := func(format string, a ...any) error {
+		return fmt.Errorf("%s:%d: invalid object file: %s", filename, lineNumber, fmt.Sprintf(format, a...))
+	}
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if lineNumber == 1 {
+			if line != objectHeader {
+				return nil, errorf("expected %q", objectHeader)
+			}
+			continue
+		}
+		kind, rest, _ := strings.Cut(line, " ")
+		// the position comes last, and its file name may contain spaces
+		fields := strings.SplitN(rest, " ", positionField[kind]+1)
+		var err error
+		switch {
+		case kind == "source":
+			o.Source = rest
+		case kind == "global" && len(fields) == 3:
+			var s Symbol
+			s.Name = fields[0]
+			var address uint64
+			address, err = strconv.ParseUint(fields[1], 10, 16)
+			s.Address = uint(address)
+			if err == nil && s.Address > maxValue {
+				return nil, errorf("address out of range: %d", s.Address)
+			}
+			if err == nil {
+				s.Pos, err = parsePos(fields[2])
+			}
+			o.Globals = append(o.Globals, s)
+		case kind == "extern" && len(fields) == 2:
+			s := Symbol{Name: fields[0]}
+			s.Pos, err = parsePos(fields[1])
+			o.Externs = append(o.Externs, s)
+		case kind == "code" && len(fields) == 1:
+			var code uint64
+			code, err = strconv.ParseUint(fields[0], 2, 16)
+			o.Code = append(o.Code, uint16(code))
+		case (kind == "reloc" || kind == "ref") && len(fields) == 3:
+			var r Reference
+			r.Index, err = strconv.Atoi(fields[0])
+			r.Symbol = fields[1]
+			if err == nil {
+				r.Pos, err = parsePos(fields[2])
+			}
+			if kind == "reloc" {
+				o.Relocations = append(o.Relocations, r)
+			} else {
+				o.References = append(o.References, r)
+			}
+		default:
+			return nil, errorf("%q", line)
+		}
+		if err != nil {
+			return nil, errorf("%q", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNumber == 0 {
+		return nil, errorf("empty file")
+	}
+	for _, r := range slices.Concat(o.Relocations, o.References) {
+		if r.Index < 0 || r.Index >= len(o.Code) {
+			return nil, fmt.Errorf("%s: invalid object file: index %d out of range", filename, r.Index)
+		}
+	}
+	return o, nil
+}
+
+// positionField is the index of the position among the fields of each kind of line in an object
+// file.
+var positionField = map[string]int{
+	"global": 2,
+	"extern": 1,
+	"reloc":  2,
+	"ref":    2,
+}
+
+// formatPos formats a position for an object file as "file":line:column, with the file name
+// quoted so it can contain spaces and colons.
+func formatPos(p Pos) string {
+	return fmt.Sprintf("%s:%d:%d", strconv.Quote(p.File), p.Line, p.Column)
+}
+
+// parsePos parses a position written by formatPos.
+func parsePos(s string) (Pos, error) {
+	quoted, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return Pos{}, fmt.Errorf("invalid position: %q", s)
+	}
+	file, _ := strconv.Unquote(quoted)
+	rest, ok1 := strings.CutPrefix(s[len(quoted):], ":")
+	lineText, columnText, ok2 := strings.Cut(rest, ":")
+	line, err1 := strconv.Atoi(lineText)
+	column, err2 := strconv.Atoi(columnText)
+	if !ok1 || !ok2 || err1 != nil || err2 != nil {
+		return Pos{}, fmt.Errorf("invalid position: %q", s)
+	}
+	return Pos{File: file, Line: line, Column: column}, nil
+}
@@ -0,0 +1,214 @@
+package asm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Optimization levels for Options.Optimize.
+const (
+	// OptimizeNone turns the optimizer off.
+	OptimizeNone = iota
+
+	// OptimizeLocal removes redundant instructions within straight-line code: A-instructions
+	// whose value is never used, loads of a value that's already in A, and increments that are
+	// undone right away.
+	OptimizeLocal
+
+	// OptimizeUnreachable also removes instructions after an unconditional jump that no label
+	// reaches.
+	OptimizeUnreachable
+
+	// OptimizeSize also replaces repeated sequences of instructions with calls to shared
+	// subroutines, which makes the program smaller but slower.
+	OptimizeSize
+)
+
+// An optimization rule removes redundant instructions from a program. The optimizer applies the
+// rules again and again until none of them changes anything.
+//
+// Labels are barriers for all rules: code after a label can be reached by a jump, so the rules
+// can't assume anything about the values of the registers there. Note that optimizing changes
+// the addresses of instructions, so programs that jump to numeric addresses instead of labels
+// can't be optimized.
+type rule struct {
+	name  string
+	level int
+
+	// apply marks the statements the rule removes.
+	apply func(statements []Statement, removed []bool)
+}
+
+// rules are sorted by level, so the rules for a level are always a prefix of the list.
+var rules = []rule{
+	{"repeated-load", OptimizeLocal, repeatedLoad},
+	{"cancel", OptimizeLocal, cancel},
+	{"unused-load", OptimizeLocal, unusedLoad},
+	{"unreachable", OptimizeUnreachable, unreachable},
+}
+
+// A saving says how many instructions an optimization rule removed, and how many cycles it added
+// to the program's running time if each place it changed runs once.
+type saving struct {
+	rule         string
+	instructions int
+	cycles       int
+}
+
+// optimize applies the optimization rules up to the given level and returns the optimized
+// program, along with the number of instructions each rule saved. At OptimizeSize, the program is
+// outlined after the rules have done their work.
+func optimize(statements []Statement, level int) ([]Statement, []saving) {
+	var savings []saving
+	for _, r := range rules {
+		if r.level <= level {
+			savings = append(savings, saving{rule: r.name})
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, r := range rules {
+			if r.level > level {
+				continue
+			}
+			removed := make([]bool, len(statements))
+			r.apply(statements, removed)
+			var kept []Statement
+			for j, s := range statements {
+				if !removed[j] {
+					kept = append(kept, s)
+				}
+			}
+			if n := len(statements) - len(kept); n > 0 {
+				savings[i].instructions += n
+				statements = kept
+				changed = true
+			}
+		}
+	}
+	if level >= OptimizeSize {
+		var s saving
+		statements, s = outline(statements)
+		savings = append(savings, s)
+	}
+	return statements, savings
+}
+
+// writeSavings writes a report of the savings returned by optimize.
+func writeSavings(w io.Writer, savings []saving) error {
+	total, cycles := 0, 0
+	for _, s := range savings {
+		if _, err := fmt.Fprintf(w, "%-14s %6d\n", s.rule, s.instructions); err != nil {
+			return err
+		}
+		total += s.instructions
+		cycles += s.cycles
+	}
+	if _, err := fmt.Fprintf(w, "%-14s %6d\n", "total", total); err != nil {
+		return err
+	}
+	if cycles > 0 {
+		_, err := fmt.Fprintf(w, "%-14s %6d\n", "cycles added", cycles)
+		return err
+	}
+	return nil
+}
+
+// next returns the index of the next instruction after index i, or -1 if there's a label first or
+// the program ends. Statements removed by the current rule are skipped.
+func next(statements []Statement, removed []bool, i int) int {
+	for j := i + 1; j < len(statements); j++ {
+		switch statements[j].Instruction.(type) {
+		case LInstruction:
+			return -1
+		case SymbolicAInstruction, DecimalAInstruction, CInstruction:
+			if !removed[j] {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func isA(s Statement) bool {
+	switch s.Instruction.(type) {
+	case SymbolicAInstruction, DecimalAInstruction:
+		return true
+	}
+	return false
+}
+
+// repeatedLoad removes A-instructions that load the value A already holds, for example the second
+// @R13 in "@R13, M=D, @R13, D=M".
+func repeatedLoad(statements []Statement, removed []bool) {
+	for i, s := range statements {
+		if removed[i] || !isA(s) {
+			continue
+		}
+		j := next(statements, removed, i)
+		for j >= 0 {
+			c, ok := statements[j].Instruction.(CInstruction)
+			if !ok || strings.Contains(c.Dest, "A") {
+				break
+			}
+			j = next(statements, removed, j)
+		}
+		if j >= 0 && statements[j].Instruction == s.Instruction {
+			removed[j] = true
+		}
+	}
+}
+
+// cancel removes pairs of instructions that undo each other, like "M=M+1, M=M-1".
+func cancel(statements []Statement, removed []bool) {
+	for i, s := range statements {
+		if removed[i] {
+			continue
+		}
+		first, ok := s.Instruction.(CInstruction)
+		if !ok || first.Jump != "" {
+			continue
+		}
+		j := next(statements, removed, i)
+		if j < 0 {
+			continue
+		}
+		second, ok := statements[j].Instruction.(CInstruction)
+		if !ok || second.Jump != "" || second.Dest != first.Dest {
+			continue
+		}
+		r := first.Dest
+		increment, decrement := r+"+1", r+"-1"
+		if len(r) == 1 && (first.Comp == increment && second.Comp == decrement ||
+			first.Comp == decrement && second.Comp == increment) {
+			removed[i] = true
+			removed[j] = true
+		}
+	}
+}
+
+// unusedLoad removes A-instructions followed directly by another A-instruction, which overwrites
+// the value before it's used.
+func unusedLoad(statements []Statement, removed []bool) {
+	for i, s := range statements {
+		if removed[i] || !isA(s) {
+			continue
+		}
+		if j := next(statements, removed, i); j >= 0 && isA(statements[j]) {
+			removed[i] = true
+		}
+	}
+}
+
+// unreachable removes instructions that follow an unconditional jump, up to the next label.
+func unreachable(statements []Statement, removed []bool) {
+	for i, s := range statements {
+		if c, ok := s.Instruction.(CInstruction); !ok || c.Jump != "JMP" || removed[i] {
+			continue
+		}
+		for j := next(statements, removed, i); j >= 0; j = next(statements, removed, j) {
+			removed[j] = true
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package asm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	cases := []struct {
+		level           int
+		source, want    string
+		repeated        int
+		cancelled       int
+		unused          int
+		unreachableCode int
+	}{
+		{
+			level:    OptimizeLocal,
+			source:   "@SP\nM=M+1\n@SP\nM=M-1\n@R13\nM=D\n@R13\nD=M\n",
+			want:     "@R13\nM=D\nD=M\n",
+			repeated: 2, cancelled: 2, unused: 1,
+		},
+		{
+			// labels are barriers
+			level:  OptimizeLocal,
+			source: "@R13\nM=D\n(L)\n@R13\nD=M\n@1\n(M)\n@2\n",
+			want:   "@R13\nM=D\n(L)\n@R13\nD=M\n@1\n(M)\n@2\n",
+		},
+		{
+			// writing to A ends the range where A is known
+			level:  OptimizeLocal,
+			source: "@R13\nAM=M+1\n@R13\nD=M\n",
+			want:   "@R13\nAM=M+1\n@R13\nD=M\n",
+		},
+		{
+			level:           OptimizeUnreachable,
+			source:          "@END\n0;JMP\nD=0\n@5\nM=D\n(END)\n@END\n0;JMP\n",
+			want:            "@END\n0;JMP\n(END)\n@END\n0;JMP\n",
+			unreachableCode: 3,
+		},
+		{
+			level:  OptimizeLocal,
+			source: "@END\n0;JMP\nD=0\n",
+			want:   "@END\n0;JMP\nD=0\n",
+		},
+	}
+	for _, c := range cases {
+		statements, errs := parse(sourceLines(t, c.source))
+		if len(errs) > 0 {
+			t.Fatalf("parse returned errors:\n%v", errs)
+		}
+		optimized, savings := optimize(statements, c.level)
+		var b strings.Builder
+		for _, s := range optimized {
+			b.WriteString(s.text + "\n")
+		}
+		if got := b.String(); got != c.want {
+			t.Errorf("optimize(%q) returned:\n%s\nwant:\n%s", c.source, got, c.want)
+		}
+		want := []saving{
+			{rule: "repeated-load", instructions: c.repeated},
+			{rule: "cancel", instructions: c.cancelled},
+			{rule: "unused-load", instructions: c.unused},
+		}
+		if c.level >= OptimizeUnreachable {
+			want = append(want, saving{rule: "unreachable", instructions: c.unreachableCode})
+		}
+		if !reflect.DeepEqual(savings, want) {
+			t.Errorf("optimize(%q) returned savings %v, want %v", c.source, savings, want)
+		}
+	}
+}
+
+func TestRunOptimize(t *testing.T) {
+	source := "@SP\nM=M+1\n@SP\nM=M-1\n@R13\nM=D\n@R13\nD=M\n"
+	var output, report strings.Builder
+	options := Options{Optimize: OptimizeLocal, Savings: &report}
+	err := Run("test.asm", strings.NewReader(source), &output, options)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := "0000000000001101\n1110001100001000\n1111110000010000\n"
+	if got := output.String(); got != want {
+		t.Errorf("Run produced:\n%s\nwant:\n%s", got, want)
+	}
+	wantReport := `repeated-load       2
+cancel              2
+unused-load         1
+total               5
+`
+	if got := report.String(); got != wantReport {
+		t.Errorf("Run wrote report:\n%s\nwant:\n%s", got, wantReport)
+	}
+}
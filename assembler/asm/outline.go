@@ -0,0 +1,358 @@
+package asm
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Outlining (procedural abstraction) replaces repeated sequences of instructions with calls to a
+// shared subroutine. A call passes the return address in D and the subroutine saves it in a
+// variable:
+//
+//	    @__outline.0$ret.1     (__outline.0)
+//	    D=A                        @__outline.ret
+//	    @__outline.0               M=D
+//	    0;JMP                      ...
+//	(__outline.0$ret.1)            @__outline.ret
+//	                               A=M
+//	                               0;JMP
+//
+// That clobbers A and D, so a sequence is only outlined if it writes both before reading them,
+// and only where the instruction after it loads A. A sequence that ends with an unconditional
+// jump, like the return from a VM function, doesn't return; it's replaced with a plain jump, which
+// leaves D alone.
+//
+// Sequences don't contain labels or conditional jumps, so each one is straight-line code. Outlined
+// subroutines never call each other, so one variable is enough for the return address.
+
+const (
+	// maxOutline is the length of the longest sequence outlining looks for.
+	maxOutline = 256
+
+	callSize           = 4 // instructions at a call site
+	subroutineOverhead = 5 // instructions to save the return address and jump back
+	tailCallSize       = 2 // instructions at a call site for a sequence that ends with a jump
+
+	callCycles     = callSize + subroutineOverhead
+	tailCallCycles = tailCallSize
+)
+
+// A sequence is a group of identical sequences of instructions found in the program.
+type sequence struct {
+	length int
+	tail   bool  // ends with an unconditional jump
+	valid  bool  // writes D before reading it (the call overwrites D), or is a tail
+	starts []int // indexes of the first statements of the sequences
+}
+
+// saving returns the number of instructions saved by outlining n sequences.
+func (s *sequence) saving(n int) int {
+	if s.tail {
+		return n*s.length - (n*tailCallSize + s.length)
+	}
+	return n*s.length - (n*callSize + s.length + subroutineOverhead)
+}
+
+// outline replaces repeated sequences of instructions with calls to subroutines at the end of the
+// program. It returns the new program and the net number of instructions saved, along with the
+// cycles added by running each call once.
+func outline(statements []Statement) ([]Statement, saving) {
+	// runs of consecutive instructions without labels or conditional jumps, as start and end
+	// indexes
+	type run struct{ start, end int }
+	var runs []run
+	start := -1
+	for i, s := range statements {
+		c, isC := s.Instruction.(CInstruction)
+		code := isA(s) || isC && (c.Jump == "" || c.Jump == "JMP")
+		if code && start < 0 {
+			start = i
+		}
+		if !code && start >= 0 {
+			runs = append(runs, run{start, i})
+			start = -1
+		}
+		if isC && c.Jump == "JMP" && start >= 0 {
+			runs = append(runs, run{start, i + 1})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		runs = append(runs, run{start, len(statements)})
+	}
+	runEnd := make([]int, len(statements))
+	for _, r := range runs {
+		for i := r.start; i < r.end; i++ {
+			runEnd[i] = r.end
+		}
+	}
+
+	// loadsA reports whether the next instruction after index i loads A, so A is dead there
+	loadsA := func(i int) bool {
+		for ; i < len(statements); i++ {
+			switch statements[i].Instruction.(type) {
+			case SymbolicAInstruction, DecimalAInstruction:
+				return true
+			case CInstruction:
+				return false
+			}
+		}
+		return true
+	}
+
+	// Find all repeated sequences, starting with pairs of instructions and extending each group
+	// of identical sequences by one instruction at a time as long as at least two stay the same.
+	type state struct{ aRead, aWritten, dRead, dWritten bool }
+	states := make(map[int]state)
+	update := func(st state, instruction Instruction) state {
+		uses, defs := registers(instruction)
+		st.aRead = st.aRead || uses&regA != 0 && !st.aWritten
+		st.dRead = st.dRead || uses&regD != 0 && !st.dWritten
+		st.aWritten = st.aWritten || defs&regA != 0
+		st.dWritten = st.dWritten || defs&regD != 0
+		return st
+	}
+	var groups [][]int
+	byFirst := make(map[Instruction][]int)
+	var firsts []Instruction
+	for _, r := range runs {
+		for i := r.start; i < r.end; i++ {
+			st := update(state{}, statements[i].Instruction)
+			if st.aRead {
+				continue
+			}
+			states[i] = st
+			if byFirst[statements[i].Instruction] == nil {
+				firsts = append(firsts, statements[i].Instruction)
+			}
+			byFirst[statements[i].Instruction] = append(byFirst[statements[i].Instruction], i)
+		}
+	}
+	for _, instruction := range firsts {
+		if len(byFirst[instruction]) > 1 {
+			groups = append(groups, byFirst[instruction])
+		}
+	}
+	var sequences []*sequence
+	for length := 2; length <= maxOutline && len(groups) > 0; length++ {
+		var next [][]int
+		for _, group := range groups {
+			extended := make(map[Instruction][]int)
+			var order []Instruction
+			for _, i := range group {
+				j := i + length - 1
+				if j >= runEnd[i] {
+					continue
+				}
+				instruction := statements[j].Instruction
+				st := update(states[i], instruction)
+				if st.aRead {
+					continue
+				}
+				states[i] = st
+				if extended[instruction] == nil {
+					order = append(order, instruction)
+				}
+				extended[instruction] = append(extended[instruction], i)
+			}
+			for _, instruction := range order {
+				starts := extended[instruction]
+				if len(starts) < 2 {
+					continue
+				}
+				next = append(next, starts)
+				c, ok := instruction.(CInstruction)
+				tail := ok && c.Jump == "JMP"
+				st := states[starts[0]]
+				sequences = append(sequences, &sequence{
+					length: length,
+					tail:   tail,
+					valid:  tail || st.dWritten && !st.dRead,
+					starts: starts,
+				})
+			}
+		}
+		groups = next
+	}
+
+	// pick returns the starts of the sequences in a group that can be outlined: ones that don't
+	// overlap each other or sequences that have been outlined already
+	used := make([]bool, len(statements))
+	pick := func(s *sequence) []int {
+		var starts []int
+		end := 0
+		for _, i := range s.starts {
+			if i < end || !s.tail && !loadsA(i+s.length) ||
+				slices.Contains(used[i:i+s.length], true) {
+				continue
+			}
+			starts = append(starts, i)
+			end = i + s.length
+		}
+		return starts
+	}
+
+	// Outline the sequences that save the most first.
+	sequences = slices.DeleteFunc(sequences, func(s *sequence) bool {
+		return !s.valid || s.saving(len(s.starts)) <= 0
+	})
+	estimates := make(map[*sequence]int)
+	for _, s := range sequences {
+		estimates[s] = s.saving(len(pick(s)))
+	}
+	slices.SortStableFunc(sequences, func(a, b *sequence) int {
+		return cmp.Or(cmp.Compare(estimates[b], estimates[a]), cmp.Compare(b.length, a.length))
+	})
+	type call struct {
+		subroutine int
+		sequence   *sequence
+	}
+	calls := make(map[int]call) // by start index
+	var outlined []*sequence
+	result := saving{rule: "outline"}
+	for _, s := range sequences {
+		starts := pick(s)
+		if s.saving(len(starts)) <= 0 {
+			continue
+		}
+		s.starts = starts
+		for _, i := range starts {
+			for j := i; j < i+s.length; j++ {
+				used[j] = true
+			}
+			calls[i] = call{len(outlined), s}
+		}
+		outlined = append(outlined, s)
+		result.instructions += s.saving(len(starts))
+		if s.tail {
+			result.cycles += len(starts) * tailCallCycles
+		} else {
+			result.cycles += len(starts) * callCycles
+		}
+	}
+	if len(outlined) == 0 {
+		return statements, result
+	}
+
+	// The subroutines go at the end of the program. If the program doesn't end with a jump, it
+	// would run into them, so it gets an endless loop first.
+	prefix := outlinePrefix(statements)
+	var last Instruction
+	for _, s := range statements {
+		if isA(s) || isC(s) {
+			last = s.Instruction
+		}
+	}
+	guard := false
+	if c, ok := last.(CInstruction); !ok || c.Jump != "JMP" {
+		guard = true
+		result.instructions -= 3
+	}
+	if result.instructions <= 0 {
+		return statements, saving{rule: "outline"}
+	}
+
+	var program []Statement
+	returns := 0
+	for i := 0; i < len(statements); i++ {
+		c, ok := calls[i]
+		if !ok {
+			program = append(program, statements[i])
+			continue
+		}
+		first := statements[i]
+		name := fmt.Sprintf("%s.%d", prefix, c.subroutine)
+		if c.sequence.tail {
+			program = append(program, generated(first, SymbolicAInstruction{name}), generated(first, CInstruction{Comp: "0", Jump: "JMP"}))
+		} else {
+			ret := fmt.Sprintf("%s$ret.%d", name, returns)
+			returns++
+			program = append(program,
+				generated(first, SymbolicAInstruction{ret}),
+				generated(first, CInstruction{Dest: "D", Comp: "A"}),
+				generated(first, SymbolicAInstruction{name}),
+				generated(first, CInstruction{Comp: "0", Jump: "JMP"}),
+				generated(first, LInstruction{ret}))
+		}
+		i += c.sequence.length - 1
+	}
+
+	// statements at the end of the program get line 0, so they don't show up in the listing
+	end := func(s Statement, instruction Instruction) Statement {
+		s = generated(s, instruction)
+		s.line = 0
+		return s
+	}
+	if guard {
+		s := statements[len(statements)-1]
+		label := prefix + ".end"
+		program = append(program,
+			end(s, LInstruction{label}),
+			end(s, SymbolicAInstruction{label}),
+			end(s, CInstruction{Comp: "0", Jump: "JMP"}))
+	}
+	ret := prefix + ".ret"
+	for n, s := range outlined {
+		first := statements[s.starts[0]]
+		program = append(program, end(first, LInstruction{fmt.Sprintf("%s.%d", prefix, n)}))
+		if !s.tail {
+			program = append(program,
+				end(first, SymbolicAInstruction{ret}),
+				end(first, CInstruction{Dest: "M", Comp: "D"}))
+		}
+		for _, body := range statements[s.starts[0] : s.starts[0]+s.length] {
+			body.line = 0
+			program = append(program, body)
+		}
+		if !s.tail {
+			program = append(program,
+				end(first, SymbolicAInstruction{ret}),
+				end(first, CInstruction{Dest: "A", Comp: "M"}),
+				end(first, CInstruction{Comp: "0", Jump: "JMP"}))
+		}
+	}
+	return program, result
+}
+
+func isC(s Statement) bool {
+	_, ok := s.Instruction.(CInstruction)
+	return ok
+}
+
+// generated returns a statement for an instruction added by the optimizer, at the position of s.
+func generated(s Statement, instruction Instruction) Statement {
+	s.Instruction = instruction
+	s.text = instruction.String()
+	s.columns = nil
+	return s
+}
+
+// outlinePrefix returns a prefix for the symbols outlining adds that doesn't clash with any symbol
+// in the program.
+func outlinePrefix(statements []Statement) string {
+	prefix := "__outline"
+	for {
+		clash := false
+		for _, s := range statements {
+			var symbol string
+			switch i := s.Instruction.(type) {
+			case SymbolicAInstruction:
+				symbol = i.Symbol
+			case LInstruction:
+				symbol = i.Symbol
+			case EquDirective:
+				symbol = i.Symbol
+			}
+			if strings.HasPrefix(symbol, prefix) {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			return prefix
+		}
+		prefix = "_" + prefix
+	}
+}
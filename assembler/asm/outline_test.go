@@ -0,0 +1,137 @@
+package asm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lfritz/nand2tetris/hackrun/hack"
+)
+
+// push is a sequence that the translator emits for "push local 2": it writes A and D before
+// reading them, so it can be outlined.
+const push = "@LCL\nD=M\n@2\nA=D+A\nD=M\n@SP\nA=M\nM=D\n@SP\nM=M+1\n@LCL\nD=M\n@3\nA=D+A\nD=M\n@SP\nA=M\nM=D\n@SP\nM=M+1\n"
+
+// addD adds D to the top of the stack ten times, reading D without writing it first.
+var addD = strings.Repeat("@SP\nA=M\nM=M+D\n", 10)
+
+func TestOutline(t *testing.T) {
+	cases := []struct {
+		source, want string
+		saved        int
+		cycles       int
+	}{
+		{
+			source: push + "@R0\n" + push + "(END)\n@END\n0;JMP\n",
+			want: "@__outline.0$ret.0\nD=A\n@__outline.0\n0;JMP\n(__outline.0$ret.0)\n@R0\n" +
+				"@__outline.0$ret.1\nD=A\n@__outline.0\n0;JMP\n(__outline.0$ret.1)\n(END)\n@END\n0;JMP\n" +
+				"(__outline.0)\n@__outline.ret\nM=D\n" + push + "@__outline.ret\nA=M\n0;JMP\n",
+			saved:  2*20 - (2*4 + 20 + 5),
+			cycles: 2 * 9,
+		},
+		{
+			// a sequence that ends with a jump is jumped to, and the program gets an endless loop
+			// before the subroutine
+			source: "(A)\n" + push + "@A\n0;JMP\n(B)\n" + push + "@A\n0;JMP\n(C)\n" + push + "@A\n0;JMP\n@B\nD=A\n@C\n",
+			want: "(A)\n@__outline.0\n0;JMP\n(B)\n@__outline.0\n0;JMP\n(C)\n@__outline.0\n0;JMP\n@B\nD=A\n@C\n" +
+				"(__outline.end)\n@__outline.end\n0;JMP\n(__outline.0)\n" + push + "@A\n0;JMP\n",
+			saved:  3*22 - (3*2 + 22) - 3,
+			cycles: 3 * 2,
+		},
+		{
+			// D is read before it's written
+			source: addD + "@R0\n" + addD + "(END)\n@END\n0;JMP\n",
+			want:   addD + "@R0\n" + addD + "(END)\n@END\n0;JMP\n",
+		},
+		{
+			// A is used after the whole sequence, so only the part before the last load of A is
+			// outlined
+			source: push + "M=0\n" + push + "D=A\n(END)\n@END\n0;JMP\n",
+			want: "@__outline.0$ret.0\nD=A\n@__outline.0\n0;JMP\n(__outline.0$ret.0)\n@SP\nM=M+1\nM=0\n" +
+				"@__outline.0$ret.1\nD=A\n@__outline.0\n0;JMP\n(__outline.0$ret.1)\n@SP\nM=M+1\nD=A\n" +
+				"(END)\n@END\n0;JMP\n(__outline.0)\n@__outline.ret\nM=D\n" +
+				strings.TrimSuffix(push, "@SP\nM=M+1\n") + "@__outline.ret\nA=M\n0;JMP\n",
+			saved:  2*18 - (2*4 + 18 + 5),
+			cycles: 2 * 9,
+		},
+	}
+	for _, c := range cases {
+		statements, errs := parse(sourceLines(t, c.source))
+		if len(errs) > 0 {
+			t.Fatalf("parse returned errors:\n%v", errs)
+		}
+		outlined, s := outline(statements)
+		var b strings.Builder
+		for _, s := range outlined {
+			b.WriteString(s.text + "\n")
+		}
+		if got := b.String(); got != c.want {
+			t.Errorf("outline(%q) returned:\n%s\nwant:\n%s", c.source, got, c.want)
+		}
+		if s.instructions != c.saved || s.cycles != c.cycles {
+			t.Errorf("outline(%q) saved %d instructions and added %d cycles, want %d and %d",
+				c.source, s.instructions, s.cycles, c.saved, c.cycles)
+		}
+	}
+}
+
+// runHack assembles source at the given optimization level and runs it on the emulated Hack
+// computer for at most the given number of cycles. It returns the computer and the size of the
+// program.
+func runHack(t *testing.T, source string, level, cycles int) (*hack.Computer, int) {
+	t.Helper()
+	var output strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{Optimize: level, Format: FormatRaw})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	code := []byte(output.String())
+	program := make([]uint16, len(code)/2)
+	for i := range program {
+		program[i] = binary.BigEndian.Uint16(code[2*i:])
+	}
+	c, err := hack.NewComputer(program)
+	if err != nil {
+		t.Fatalf("NewComputer returned error: %v", err)
+	}
+	c.Run(cycles)
+	return c, len(program)
+}
+
+func TestOutlineRun(t *testing.T) {
+	zero := ""
+	for i := 20; i < 28; i++ {
+		zero += fmt.Sprintf("@%d\nM=0\n", i)
+	}
+	cases := []struct {
+		source   string
+		want     map[int]int16 // RAM contents after running
+		outlined bool
+	}{
+		{
+			// the sequence never writes D, so outlining it would clobber the value stored after it
+			source: "@7\nD=A\n" + zero + "@100\nM=D\n" + zero + "@101\nM=D\n" + zero + "@102\nM=D\n(END)\n@END\n0;JMP\n",
+			want:   map[int]int16{100: 7, 101: 7, 102: 7},
+		},
+		{
+			// pushes local 2 and local 3 twice, with LCL at 256 and SP at 300
+			source: "@300\nD=A\n@SP\nM=D\n@256\nD=A\n@LCL\nM=D\n@258\nM=1\n@259\nM=-1\n" +
+				push + "@R0\n" + push + "(END)\n@END\n0;JMP\n",
+			want:     map[int]int16{0: 304, 300: 1, 301: -1, 302: 1, 303: -1},
+			outlined: true,
+		},
+	}
+	for _, c := range cases {
+		_, plainSize := runHack(t, c.source, OptimizeNone, 0)
+		computer, size := runHack(t, c.source, OptimizeSize, 10000)
+		for address, want := range c.want {
+			if got := int16(computer.RAM[address]); got != want {
+				t.Errorf("outlined program %q left %d in RAM[%d], want %d", c.source, got, address, want)
+			}
+		}
+		if outlined := size < plainSize; outlined != c.outlined {
+			t.Errorf("outlining program %q changed size from %d to %d", c.source, plainSize, size)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Parser implements a parser for Hack assembly code.
+type Parser struct {
+	lines   []sourceLine
+	index   int // index of the current line, starting at 1
+	current string
+	columns []int     // column in the source line of each byte in current
+	lexErr  *lexError // error lexing the current line
+	err     error
+}
+
+// NewParser creates a new parser given the name and contents of a Hack assembly file.
+//
+// To use the Parser, call Scan to get the next line, then InstructionType to get the type of the
+// current instruction, then the method for that type to get the actual instruction. Call Err after
+// using the Parser to check for read errors.
+func NewParser(filename string, r io.Reader) *Parser {
+	lines, err := readLines(filename, r)
+	return &Parser{lines: lines, err: err}
+}
+
+// newLineParser creates a new parser for lines that have already been read and preprocessed.
+func newLineParser(lines []sourceLine) *Parser {
+	return &Parser{lines: lines}
+}
+
+// Scan advances the parser to the next line of assembly code, skipping empty lines, comments, and
+// preprocessor directives. It returns false when the end of the source has been reached.
+//
+// Comments can also follow an instruction on the same line, and whitespace between the parts of
+// an instruction is ignored, so "D = M  // load" is the same as "D=M".
+func (p *Parser) Scan() bool {
+	for p.index < len(p.lines) {
+		p.index++
+		l := p.source()
+		if l.directive {
+			continue
+		}
+		p.current, p.columns, p.lexErr = normalize(l.text)
+		if p.current == "" && p.lexErr == nil {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Err returns the first error encountered reading the source, if any.
+func (p *Parser) Err() error {
+	return p.err
+}
+
+// Pos returns the position of the current instruction.
+func (p *Parser) Pos() Pos {
+	pos := p.source().pos
+	pos.Column = p.column(0)
+	return pos
+}
+
+// column returns the column in the source line for an offset in the current instruction.
+func (p *Parser) column(offset int) int {
+	if len(p.columns) == 0 && p.lexErr != nil {
+		return p.lexErr.column
+	}
+	return columnAt(p.columns, offset)
+}
+
+// columnAt returns the column for an offset in a normalized line, given the columns returned by
+// normalize. Offsets past the end count on from the last column.
+func columnAt(columns []int, offset int) int {
+	switch {
+	case offset < len(columns):
+		return columns[offset]
+	case len(columns) > 0:
+		return columns[len(columns)-1] + 1 + offset - len(columns)
+	}
+	return 1 + offset
+}
+
+func (p *Parser) source() *sourceLine {
+	return &p.lines[p.index-1]
+}
+
+// errorf returns an error for the current instruction. Offset is the position of the problem
+// relative to the start of the instruction, with whitespace and comments removed.
+func (p *Parser) errorf(offset int, format string, a ...any) *Error {
+	return p.source().errorf(p.column(offset), format, a...)
+}
+
+// checkLine returns an error if the current line contains a character that's not valid in Hack
+// assembly.
+func (p *Parser) checkLine() error {
+	if p.lexErr == nil {
+		return nil
+	}
+	return p.source().errorf(p.lexErr.column, "%s", p.lexErr.msg)
+}
+
+// InstructionType returns the type of the current instruction. This is only valid after Scan has
+// been called and returned true.
+func (p *Parser) InstructionType() InstructionType {
+	return instructionType(p.current)
+}
+
+// SymbolicAInstruction parses and returns a symbolic A-instruction. Only valid if InstructionType
+// returns TypeASymbolic.
+func (p *Parser) SymbolicAInstruction() (SymbolicAInstruction, error) {
+	if err := p.checkLine(); err != nil {
+		return SymbolicAInstruction{}, err
+	}
+	instruction, err := parseSymbolicAInstruction(p.current)
+	if err != nil {
+		return instruction, p.errorf(0, "%v", err)
+	}
+	return instruction, nil
+}
+
+// DecimalAInstruction parses and returns a decimal A-instruction. Only valid if InstructionType
+// returns TypeADecimal.
+func (p *Parser) DecimalAInstruction() (DecimalAInstruction, error) {
+	if err := p.checkLine(); err != nil {
+		return DecimalAInstruction{}, err
+	}
+	instruction, err := parseDecimalAInstruction(p.current)
+	if err != nil {
+		return instruction, p.errorf(1, "%v", err)
+	}
+	return instruction, nil
+}
+
+// CInstruction parses and returns a C-instruction. Only valid if InstructionType returns TypeC.
+func (p *Parser) CInstruction() (CInstruction, error) {
+	if err := p.checkLine(); err != nil {
+		return CInstruction{}, err
+	}
+	instruction, err := parseCInstruction(p.current)
+	if err != nil {
+		return instruction, p.errorf(0, "%v", err)
+	}
+	return instruction, nil
+}
+
+// LInstruction parses and returns a label pseudo-instruction. Only valid if InstructionType
+// returns TypeL.
+func (p *Parser) LInstruction() (LInstruction, error) {
+	if err := p.checkLine(); err != nil {
+		return LInstruction{}, err
+	}
+	instruction, err := parseLInstruction(p.current)
+	if err != nil {
+		offset := 0
+		if strings.HasPrefix(p.current, "(") && strings.HasSuffix(p.current, ")") {
+			// the parentheses are fine, so it's the name that's invalid
+			offset = 1
+		}
+		return instruction, p.errorf(offset, "%v", err)
+	}
+	return instruction, nil
+}
+
+// EquDirective parses and returns a .equ directive. Only valid if InstructionType returns TypeEqu.
+func (p *Parser) EquDirective() (EquDirective, error) {
+	if err := p.checkLine(); err != nil {
+		return EquDirective{}, err
+	}
+	directive, err := parseEquDirective(p.current)
+	if err != nil {
+		return directive, p.errorf(0, "%v", err)
+	}
+	return directive, nil
+}
+
+// GlobalDirective parses and returns a .global directive. Only valid if InstructionType returns
+// TypeGlobal.
+func (p *Parser) GlobalDirective() (GlobalDirective, error) {
+	if err := p.checkLine(); err != nil {
+		return GlobalDirective{}, err
+	}
+	symbol, err := parseSymbolDirective(p.current, ".global")
+	if err != nil {
+		return GlobalDirective{}, p.errorf(0, "%v", err)
+	}
+	return GlobalDirective{Symbol: symbol}, nil
+}
+
+// ExternDirective parses and returns a .extern directive. Only valid if InstructionType returns
+// TypeExtern.
+func (p *Parser) ExternDirective() (ExternDirective, error) {
+	if err := p.checkLine(); err != nil {
+		return ExternDirective{}, err
+	}
+	symbol, err := parseSymbolDirective(p.current, ".extern")
+	if err != nil {
+		return ExternDirective{}, p.errorf(0, "%v", err)
+	}
+	return ExternDirective{Symbol: symbol}, nil
+}
+
+func instructionType(line string) InstructionType {
+	if remaining, ok := strings.CutPrefix(line, "@"); ok {
+		if validSymbol(remaining) {
+			return TypeASymbolic
+		} else {
+			return TypeADecimal
+		}
+	}
+	if strings.HasPrefix(line, "(") {
+		return TypeL
+	}
+	switch name, _ := splitDirective(line); name {
+	case ".equ":
+		return TypeEqu
+	case ".global":
+		return TypeGlobal
+	case ".extern":
+		return TypeExtern
+	}
+	return TypeC
+}
+
+func validSymbol(symbol string) bool {
+	if len(symbol) == 0 {
+		return false
+	}
+	for index, c := range symbol {
+		if !isSymbolChar(c) || index == 0 && unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSymbolChar reports whether c can appear in a symbol. Symbols can't start with a digit, though.
+func isSymbolChar(c rune) bool {
+	switch {
+	case unicode.IsLetter(c), unicode.IsDigit(c):
+		return true
+	case c == '_' || c == '.' || c == '$' || c == ':':
+		return true
+	}
+	return false
+}
+
+func parseSymbolicAInstruction(line string) (instruction SymbolicAInstruction, err error) {
+	symbol, ok := strings.CutPrefix(line, "@")
+	if !ok {
+		err = fmt.Errorf("invalid A-instruction: '%s'", line)
+		return
+	}
+	instruction = SymbolicAInstruction{
+		Symbol: symbol,
+	}
+	return
+}
+
+func parseDecimalAInstruction(line string) (instruction DecimalAInstruction, err error) {
+	number, ok := strings.CutPrefix(line, "@")
+	if !ok {
+		err = fmt.Errorf("invalid A-instruction: '%s'", line)
+		return
+	}
+	value, ok := parseNumber(number)
+	if !ok {
+		err = fmt.Errorf("invalid A-instruction: '%s'", line)
+		return
+	}
+	if value > maxValue {
+		err = fmt.Errorf("value out of range in A-instruction: %s (maximum is %d)", number, maxValue)
+		return
+	}
+	instruction = DecimalAInstruction{
+		Value: uint(value),
+	}
+	return
+}
+
+// parseNumber parses a numeric literal: a decimal number, a hexadecimal number starting with
+// "0x", a binary number starting with "0b", or a character in single quotes.
+func parseNumber(text string) (uint64, bool) {
+	if strings.HasPrefix(text, "'") {
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return 0, false
+		}
+		return uint64([]rune(s)[0]), true
+	}
+	base := 10
+	if digits, ok := strings.CutPrefix(text, "0x"); ok {
+		text, base = digits, 16
+	} else if digits, ok := strings.CutPrefix(text, "0b"); ok {
+		text, base = digits, 2
+	}
+	if text == "" || text[0] == '+' {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(text, base, 64)
+	if err != nil {
+		// ParseUint reports values that don't fit in 64 bits as out of range; treat them like
+		// any other number that's too large
+		if errors.Is(err, strconv.ErrRange) {
+			return math.MaxUint64, true
+		}
+		return 0, false
+	}
+	return value, true
+}
+
+func parseCInstruction(line string) (CInstruction, error) {
+	var dest string
+	remaining := line
+	if before, after, ok := strings.Cut(remaining, "="); ok {
+		dest = before
+		remaining = after
+	}
+	comp, jump, _ := strings.Cut(remaining, ";")
+	instruction := CInstruction{
+		Dest: dest,
+		Comp: comp,
+		Jump: jump,
+	}
+	return instruction, nil
+}
+
+func parseLInstruction(line string) (instruction LInstruction, err error) {
+	remaining, ok1 := strings.CutPrefix(line, "(")
+	symbol, ok2 := strings.CutSuffix(remaining, ")")
+	if !(ok1 && ok2) {
+		err = fmt.Errorf("invalid label declaration: '%s'", string(line))
+		return
+	}
+	if !validSymbol(symbol) {
+		err = fmt.Errorf("invalid label name: '%s'", symbol)
+		return
+	}
+	instruction = LInstruction{
+		Symbol: symbol,
+	}
+	return
+}
+
+func parseEquDirective(line string) (directive EquDirective, err error) {
+	name, args := splitDirective(line)
+	if name != ".equ" || len(args) != 2 {
+		err = fmt.Errorf("invalid .equ directive (expected .equ NAME value): '%s'", line)
+		return
+	}
+	if !validSymbol(args[0]) {
+		err = fmt.Errorf("invalid symbol name in .equ directive: '%s'", args[0])
+		return
+	}
+	directive = EquDirective{
+		Symbol: args[0],
+		Value:  args[1],
+	}
+	return
+}
+
+// parseSymbolDirective parses a directive that takes a single symbol, like ".global NAME".
+func parseSymbolDirective(line, name string) (string, error) {
+	directiveName, args := splitDirective(line)
+	if directiveName != name || len(args) != 1 {
+		return "", fmt.Errorf("invalid %s directive (expected %s NAME): '%s'", name, name, line)
+	}
+	if !validSymbol(args[0]) {
+		return "", fmt.Errorf("invalid symbol name in %s directive: '%s'", name, args[0])
+	}
+	return args[0], nil
+}
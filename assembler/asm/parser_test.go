@@ -1,4 +1,4 @@
-package internal
+package asm
 
 import (
 	"strings"
@@ -16,7 +16,7 @@ func TestParser(t *testing.T) {
 		// a C-instruction
 		M=1
 	`
-	parser := NewParser(strings.NewReader(input))
+	parser := NewParser("test.asm", strings.NewReader(input))
 
 	{
 		if !parser.Scan() {
@@ -162,3 +162,67 @@ func TestParseLInstruction(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLInstructionInvalid(t *testing.T) {
+	cases := []string{
+		"(foo",
+		"()",
+		"(123)",
+		"(123 bad)",
+	}
+	for _, c := range cases {
+		_, err := parseLInstruction(c)
+		if err == nil {
+			t.Errorf("parseLInstruction(%q) did not return error", c)
+		}
+	}
+}
+
+func TestParseDecimalAInstruction(t *testing.T) {
+	cases := []struct {
+		line string
+		want DecimalAInstruction
+	}{
+		{"@0", DecimalAInstruction{0}},
+		{"@32767", DecimalAInstruction{32767}},
+		{"@0x4000", DecimalAInstruction{16384}},
+		{"@0x7fFF", DecimalAInstruction{32767}},
+		{"@0b1010", DecimalAInstruction{10}},
+		{"@'A'", DecimalAInstruction{65}},
+		{"@' '", DecimalAInstruction{32}},
+	}
+	for _, c := range cases {
+		got, err := parseDecimalAInstruction(c.line)
+		if err != nil {
+			t.Errorf("parseDecimalAInstruction(%q) returned error: %v", c.line, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseDecimalAInstruction(%q) returned %v, want %v", c.line, got, c.want)
+		}
+	}
+}
+
+func TestParseDecimalAInstructionInvalid(t *testing.T) {
+	cases := []struct {
+		line, want string
+	}{
+		{"@32768", "value out of range in A-instruction: 32768 (maximum is 32767)"},
+		{"@0x8000", "value out of range in A-instruction: 0x8000 (maximum is 32767)"},
+		{"@99999999999999999999999", "value out of range in A-instruction: 99999999999999999999999 (maximum is 32767)"},
+		{"@0x", "invalid A-instruction: '@0x'"},
+		{"@0b102", "invalid A-instruction: '@0b102'"},
+		{"@+1", "invalid A-instruction: '@+1'"},
+		{"@'AB'", "invalid A-instruction: '@'AB''"},
+	}
+	for _, c := range cases {
+		_, err := parseDecimalAInstruction(c.line)
+		if err == nil {
+			t.Errorf("parseDecimalAInstruction(%q) did not return error", c.line)
+			continue
+		}
+		if err.Error() != c.want {
+			t.Errorf("parseDecimalAInstruction(%q) returned error %q, want %q", c.line, err, c.want)
+		}
+	}
+}
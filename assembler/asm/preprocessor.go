@@ -0,0 +1,134 @@
+package asm
+
+import (
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A preprocessor handles includes and expands macros and pseudo-instructions, turning the lines of
+// the source files into the lines of the program. Its output includes the directives, macro calls,
+// and pseudo-instructions themselves, marked as directives, so the listing can show them.
+type preprocessor struct {
+	open       func(name string) (io.ReadCloser, error)
+	files      []string        // stack of files being processed, to detect include cycles
+	included   map[string]bool // files processed so far, so each one is included only once
+	macros     map[string]*macro
+	expansions int // counts expansions, to make local labels unique
+	errs       ErrorList
+}
+
+// newPreprocessor returns a preprocessor that uses open to read included files.
+func newPreprocessor(open func(name string) (io.ReadCloser, error)) *preprocessor {
+	return &preprocessor{
+		open:     open,
+		included: make(map[string]bool),
+		macros:   make(map[string]*macro),
+	}
+}
+
+// file preprocesses the lines of a source file. If the same file was already processed, it
+// returns nothing.
+func (pp *preprocessor) file(filename string, lines []sourceLine) []sourceLine {
+	key := filepath.Clean(filename)
+	if pp.included[key] {
+		return nil
+	}
+	pp.included[key] = true
+	pp.files = append(pp.files, key)
+	output := pp.process(lines)
+	pp.files = pp.files[:len(pp.files)-1]
+	return output
+}
+
+// include handles an .include directive on line l and returns the preprocessed lines of the
+// included file. Paths are relative to the directory of the file that contains the directive.
+func (pp *preprocessor) include(l *sourceLine, args string) []sourceLine {
+	path, err := strconv.Unquote(strings.TrimSpace(args))
+	if err != nil || path == "" {
+		pp.errorf(l, "invalid .include directive (expected .include \"file.asm\")")
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(l.pos.File), path)
+	}
+	key := filepath.Clean(path)
+	if i := slices.Index(pp.files, key); i >= 0 {
+		cycle := append(slices.Clone(pp.files[i:]), key)
+		pp.errorf(l, "include cycle: %s", strings.Join(cycle, " -> "))
+		return nil
+	}
+	if pp.included[key] {
+		return nil
+	}
+
+	lines, err := readFile(pp.open, path)
+	if err != nil {
+		pp.errorf(l, "%v", err)
+		return nil
+	}
+	return pp.file(path, lines)
+}
+
+func (pp *preprocessor) errorf(l *sourceLine, format string, a ...any) {
+	pp.errs = append(pp.errs, l.errorf(l.indent()+1, format, a...))
+}
+
+func (pp *preprocessor) process(lines []sourceLine) []sourceLine {
+	var output []sourceLine
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		name, args := splitDirective(l.text)
+		switch {
+		case name == ".include":
+			l.directive = true
+			output = append(output, l)
+			_, rest, _ := strings.Cut(strings.TrimSpace(l.text), ".include")
+			rest, _, _ = strings.Cut(rest, "//")
+			output = append(output, pp.include(&l, rest)...)
+		case name == ".macro":
+			end := pp.define(lines[i:], args)
+			for j := i; j < i+end; j++ {
+				lines[j].directive = true
+				output = append(output, lines[j])
+			}
+			i += end - 1
+		case name == ".endm":
+			pp.errorf(&l, ".endm without .macro")
+			l.directive = true
+			output = append(output, l)
+		case name == ".equ" || name == ".global" || name == ".extern":
+			// handled by the assembler
+			output = append(output, l)
+		case strings.HasPrefix(name, "."):
+			pp.errorf(&l, "unknown directive: %s", name)
+			l.directive = true
+			output = append(output, l)
+		case pp.macros[name] != nil:
+			l.directive = true
+			output = append(output, l)
+			output = append(output, pp.expand(&l, pp.macros[name], args, 1)...)
+		default:
+			code, ok := pp.pseudo(&l)
+			l.directive = ok
+			output = append(output, l)
+			output = append(output, code...)
+		}
+	}
+	return output
+}
+
+// splitDirective splits a line that may contain a directive or a macro call into the name and the
+// comma- or space-separated arguments, ignoring comments.
+func splitDirective(line string) (string, []string) {
+	line, _, _ = strings.Cut(line, "//")
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
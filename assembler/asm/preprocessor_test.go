@@ -0,0 +1,104 @@
+package asm
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// openFrom returns an Open function for Options that reads files from a map.
+func openFrom(files map[string]string) func(name string) (io.ReadCloser, error) {
+	return func(name string) (io.ReadCloser, error) {
+		source, ok := files[name]
+		if !ok {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return io.NopCloser(strings.NewReader(source)), nil
+	}
+}
+
+func TestInclude(t *testing.T) {
+	files := map[string]string{
+		"prog/main.asm": `
+    @MAIN
+    0;JMP
+.include "lib/clear.asm"
+(MAIN)
+    @CLEAR
+    0;JMP
+.include "lib/clear.asm"
+`,
+		"prog/lib/clear.asm": `
+.include "../common.asm"
+(CLEAR)
+    @RAM_START
+    M=0
+`,
+		"prog/common.asm": `
+.equ RAM_START 100
+`,
+	}
+	var output strings.Builder
+	err := RunFiles([]string{"prog/main.asm"}, &output, Options{Open: openFrom(files)})
+	if err != nil {
+		t.Fatalf("RunFiles returned error: %v", err)
+	}
+	want := `0000000000000100
+1110101010000111
+0000000001100100
+1110101010001000
+0000000000000010
+1110101010000111
+`
+	got := output.String()
+	if got != want {
+		t.Errorf("RunFiles produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	files := map[string]string{
+		"a.asm": "@SUB\n0;JMP\n",
+		"b.asm": "(SUB)\n@SUB\n0;JMP\n",
+	}
+	var output strings.Builder
+	err := RunFiles([]string{"a.asm", "b.asm"}, &output, Options{Open: openFrom(files)})
+	if err != nil {
+		t.Fatalf("RunFiles returned error: %v", err)
+	}
+	want := "0000000000000010\n1110101010000111\n0000000000000010\n1110101010000111\n"
+	got := output.String()
+	if got != want {
+		t.Errorf("RunFiles produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	files := map[string]string{
+		"a.asm": ".include \"b.asm\"\n.include \"missing.asm\"\n.include b.asm\n",
+		"b.asm": "D=M\n  .include \"a.asm\"\nD=X\n",
+	}
+	var output strings.Builder
+	err := RunFiles([]string{"a.asm"}, &output, Options{Open: openFrom(files)})
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("RunFiles returned %v, want an ErrorList", err)
+	}
+	want := []string{
+		"a.asm:2:1: open missing.asm: file does not exist",
+		"a.asm:3:1: invalid .include directive (expected .include \"file.asm\")",
+		"b.asm:2:3: include cycle: a.asm -> b.asm -> a.asm",
+		"b.asm:3:3: invalid comp field in C-instruction: \"X\"",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("RunFiles returned %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, e := range errs {
+		got := e.Pos.String() + ": " + e.Msg
+		if got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Print writes statements to w as Hack assembly, one per line. Labels are written at the start of
+// the line, other statements indented by four spaces, the way the disassembler writes them.
+func Print(w io.Writer, statements []Statement) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range statements {
+		if _, ok := s.Instruction.(LInstruction); ok {
+			fmt.Fprintf(bw, "%v\n", s.Instruction)
+		} else {
+			fmt.Fprintf(bw, "    %v\n", s.Instruction)
+		}
+	}
+	return bw.Flush()
+}
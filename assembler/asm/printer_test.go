@@ -0,0 +1,49 @@
+package asm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseAndPrint(t *testing.T) {
+	source := `// sum
+.equ N 3
+(LOOP)
+  @N
+	DM=D+M;JGT
+  JMP LOOP
+`
+	statements, err := Parse("test.asm", strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if pos := statements[3].Pos; pos != (Pos{"test.asm", 5, 2}) {
+		t.Errorf("statement 3 has position %v, want test.asm:5:2", pos)
+	}
+	var b strings.Builder
+	if err := Print(&b, statements); err != nil {
+		t.Fatalf("Print returned error: %v", err)
+	}
+	want := `    .equ N 3
+(LOOP)
+    @N
+    DM=D+M;JGT
+    @LOOP
+    0;JMP
+`
+	if got := b.String(); got != want {
+		t.Errorf("Print wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	statements, err := Parse("test.asm", strings.NewReader("@1\n(bad\n.foo\nD=M\n"))
+	var errs ErrorList
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Parse returned %v, want two errors", err)
+	}
+	if len(statements) != 2 {
+		t.Errorf("Parse returned %d statements, want 2", len(statements))
+	}
+}
@@ -0,0 +1,159 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// conditionalJumps are the pseudo-instructions for conditional jumps.
+var conditionalJumps = map[string]bool{
+	"JEQ": true,
+	"JNE": true,
+	"JGT": true,
+	"JGE": true,
+	"JLT": true,
+	"JLE": true,
+}
+
+// pseudo expands line l if it contains a pseudo-instruction. It returns the lines of the expansion
+// and true, or false if l doesn't contain a pseudo-instruction. Errors in the arguments are
+// reported and produce an empty expansion.
+func (pp *preprocessor) pseudo(l *sourceLine) ([]sourceLine, bool) {
+	name, args, ok := pseudoFields(l.text)
+	if !ok {
+		return nil, false
+	}
+	code, err := expandPseudo(name, args)
+	if code == nil && err == nil {
+		return nil, false
+	}
+	if err != nil {
+		pp.errorf(l, "%v", err)
+		return nil, true
+	}
+	indent := l.text[:l.indent()]
+	var output []sourceLine
+	for _, instruction := range code {
+		output = append(output, sourceLine{
+			pos:       l.pos,
+			text:      indent + instruction,
+			call:      l,
+			expansion: "pseudo-instruction " + name,
+		})
+	}
+	return output, true
+}
+
+// pseudoFields splits a line that may contain a pseudo-instruction into its name and arguments.
+// Like instructions, pseudo-instructions go through the lexer, so whitespace only matters between
+// words: "D = @5" gives "D=@5", and "JEQ D , LOOP" gives "JEQ" with "D" and "LOOP". Commas and the
+// brackets in M[addr] aren't tokens, so the text around them is lexed separately. It returns false
+// if the line doesn't lex.
+func pseudoFields(line string) (string, []string, bool) {
+	line, _, _ = strings.Cut(line, "//")
+	var b strings.Builder
+	for {
+		i := strings.IndexAny(line, ",[]")
+		part := line
+		if i >= 0 {
+			part = line[:i]
+		}
+		text, _, err := normalize(part)
+		if err != nil {
+			return "", nil, false
+		}
+		b.WriteString(text)
+		if i < 0 {
+			break
+		}
+		if line[i] == ',' {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(line[i])
+		}
+		line = line[i+1:]
+	}
+	fields := strings.Fields(b.String())
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// expandPseudo returns the instructions for a pseudo-instruction, given its name and arguments as
+// returned by pseudoFields. It returns nil and no error if name isn't a pseudo-instruction.
+//
+// Pseudo-instructions are shorthands that the preprocessor expands into real Hack instructions:
+//
+//	D=@value      @value, D=A              load a number or the address of a symbol into D
+//	JMP label     @label, 0;JMP            jump unconditionally
+//	JEQ D, label  @label, D;JEQ            jump if D == 0; likewise JNE, JGT, JGE, JLT, and JLE
+//	PUSHD         @SP, AM=M+1, A=A-1, M=D  push D onto the stack
+//	POPD          @SP, AM=M-1, D=M         pop the top of the stack into D
+//	INC M[addr]   @addr, M=M+1             increment RAM[addr]
+//	DEC M[addr]   @addr, M=M-1             decrement RAM[addr]
+//	INC D         D=D+1                    increment a register (D, A, or M); likewise DEC
+//
+// Like macro calls, they're expanded before the assembler assigns addresses, so labels stay
+// correct. Note that most of them overwrite A.
+func expandPseudo(name string, args []string) ([]string, error) {
+	arity := func(n int, usage string) error {
+		if len(args) != n {
+			return fmt.Errorf("invalid %s pseudo-instruction (expected %s)", name, usage)
+		}
+		return nil
+	}
+
+	if value, ok := strings.CutPrefix(name, "D=@"); ok {
+		if value == "" || len(args) != 0 {
+			return nil, fmt.Errorf("invalid pseudo-instruction (expected D=@value)")
+		}
+		return []string{"@" + value, "D=A"}, nil
+	}
+
+	switch {
+	case name == "JMP":
+		if err := arity(1, "JMP label"); err != nil {
+			return nil, err
+		}
+		return []string{"@" + args[0], "0;JMP"}, nil
+	case conditionalJumps[name]:
+		if err := arity(2, name+" D, label"); err != nil {
+			return nil, err
+		}
+		if args[0] != "D" {
+			return nil, fmt.Errorf("%s can only test D, not %s", name, args[0])
+		}
+		return []string{"@" + args[1], "D;" + name}, nil
+	case name == "PUSHD":
+		if err := arity(0, "no arguments"); err != nil {
+			return nil, err
+		}
+		return []string{"@SP", "AM=M+1", "A=A-1", "M=D"}, nil
+	case name == "POPD":
+		if err := arity(0, "no arguments"); err != nil {
+			return nil, err
+		}
+		return []string{"@SP", "AM=M-1", "D=M"}, nil
+	case name == "INC" || name == "DEC":
+		if err := arity(1, name+" M[addr] or "+name+" D, A, or M"); err != nil {
+			return nil, err
+		}
+		op := "+1"
+		if name == "DEC" {
+			op = "-1"
+		}
+		switch target := args[0]; target {
+		case "D", "A", "M":
+			return []string{target + "=" + target + op}, nil
+		default:
+			address, ok := strings.CutPrefix(target, "M[")
+			address, ok2 := strings.CutSuffix(address, "]")
+			if !ok || !ok2 || address == "" {
+				return nil, fmt.Errorf("invalid operand for %s: %s (expected D, A, M, or M[addr])", name, target)
+			}
+			return []string{"@" + address, "M=M" + op}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,121 @@
+package asm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPseudoInstructions(t *testing.T) {
+	source := `
+    D=@5
+    PUSHD
+    POPD
+(LOOP)
+    INC M[i]
+    DEC D
+    JNE D, LOOP
+    JMP LOOP
+`
+	want := `
+    D=@5
+    @5
+    D=A
+    PUSHD
+    @SP
+    AM=M+1
+    A=A-1
+    M=D
+    POPD
+    @SP
+    AM=M-1
+    D=M
+(LOOP)
+    INC M[i]
+    @i
+    M=M+1
+    DEC D
+    D=D-1
+    JNE D, LOOP
+    @LOOP
+    D;JNE
+    JMP LOOP
+    @LOOP
+    0;JMP
+`
+	pp := newPreprocessor(nil)
+	lines := pp.file("test.asm", sourceLines(t, source))
+	if err := pp.errs.Err(); err != nil {
+		t.Fatalf("preprocessor returned error: %v", err)
+	}
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(l.text + "\n")
+	}
+	if got := b.String(); got != want {
+		t.Errorf("preprocessor produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPseudoInstructionSpacing(t *testing.T) {
+	// pseudo-instructions allow whitespace wherever instructions do
+	spaced := "(LOOP)\n  D = @5 // load\n\tD=@ LOOP\n  JNE D ,LOOP\n  INC M[ i ]\n  INC M [i]\n  JMP\tLOOP\n"
+	plain := "(LOOP)\n  D=@5\n  D=@LOOP\n  JNE D, LOOP\n  INC M[i]\n  INC M[i]\n  JMP LOOP\n"
+	var got, want strings.Builder
+	if err := Run("spaced.asm", strings.NewReader(spaced), &got, Options{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if err := Run("plain.asm", strings.NewReader(plain), &want, Options{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("Run produced:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+}
+
+func TestPseudoInstructionListing(t *testing.T) {
+	source := "(LOOP)\n  JMP LOOP\n"
+	var output, listing strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{Listing: &listing})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := `    0                    (LOOP)
+                           JMP LOOP
+    0  0000000000000000 +  @LOOP
+    1  1110101010000111 +  0;JMP
+`
+	if got := listing.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("listing starts with:\n%s\nwant:\n%s", got[:min(len(got), len(want))], want)
+	}
+}
+
+func TestPseudoInstructionErrors(t *testing.T) {
+	source := "JMP\nJEQ M, x\nINC M[]\nPUSHD 1\nD=@99999\n"
+	var output strings.Builder
+	err := Run("test.asm", strings.NewReader(source), &output, Options{})
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Run returned %v, want an ErrorList", err)
+	}
+	want := []string{
+		"test.asm:1:1: invalid JMP pseudo-instruction (expected JMP label)",
+		"test.asm:2:1: JEQ can only test D, not M",
+		"test.asm:3:1: invalid operand for INC: M[] (expected D, A, M, or M[addr])",
+		"test.asm:4:1: invalid PUSHD pseudo-instruction (expected no arguments)",
+		"test.asm:5:2: value out of range in A-instruction: 99999 (maximum is 32767)",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Run returned %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, e := range errs {
+		got := e.Pos.String() + ": " + e.Msg
+		if got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+	related := errs[4].Related
+	if related == nil || related.Msg != "in expansion of pseudo-instruction D=@99999" {
+		t.Errorf("error in expansion has related error %v, want one pointing at the pseudo-instruction", related)
+	}
+}
@@ -0,0 +1,72 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A sourceLine is a line of a Hack assembly program, either as it appears in a file or as produced
+// by expanding a macro or pseudo-instruction.
+type sourceLine struct {
+	pos  Pos // position of the start of the line
+	text string
+
+	// directive is set for lines that are handled by the preprocessor, like macro definitions and
+	// macro calls; the parser skips them.
+	directive bool
+
+	// For lines produced by expanding a macro or pseudo-instruction, call is the line with the
+	// call and expansion says what was expanded, for example "macro PUSHC".
+	call      *sourceLine
+	expansion string
+}
+
+// readLines reads the lines of a Hack assembly file.
+func readLines(filename string, r io.Reader) ([]sourceLine, error) {
+	var lines []sourceLine
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		lines = append(lines, sourceLine{
+			pos:  Pos{File: filename, Line: lineNumber, Column: 1},
+			text: scanner.Text(),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// readFile reads the lines of a Hack assembly file, using open to open it.
+func readFile(open func(name string) (io.ReadCloser, error), filename string) ([]sourceLine, error) {
+	f, err := open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLines(filename, f)
+}
+
+// errorf returns an error for the given column of the line. For lines produced by expanding a
+// macro or pseudo-instruction, the error points at the call as well.
+func (l *sourceLine) errorf(column int, format string, a ...any) *Error {
+	pos := l.pos
+	pos.Column = column
+	e := &Error{
+		Pos:    pos,
+		Msg:    fmt.Sprintf(format, a...),
+		Source: l.text,
+	}
+	if l.call != nil {
+		call := l.call.errorf(l.call.indent()+1, "in expansion of %s", l.expansion)
+		e.Related = call
+	}
+	return e
+}
+
+// indent returns the number of bytes of leading whitespace in the line.
+func (l *sourceLine) indent() int {
+	return len(l.text) - len(strings.TrimLeft(l.text, " \t"))
+}
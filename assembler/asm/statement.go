@@ -0,0 +1,85 @@
+package asm
+
+import (
+	"io"
+)
+
+// A Statement is an instruction, label declaration, or .equ directive parsed from one source line.
+// The assembler parses the program into statements once and then works on those, so it only needs
+// to read its input once.
+type Statement struct {
+	Pos         Pos // position where the statement starts
+	Instruction Instruction
+
+	line    int // index of the source line, starting at 1
+	source  *sourceLine
+	text    string // the statement without whitespace and comments, as returned by normalize
+	columns []int  // column in the source line of each byte in text
+}
+
+// errorf returns an error for the statement. Offset is the position of the problem relative to the
+// start of the statement.
+func (s *Statement) errorf(offset int, format string, a ...any) *Error {
+	return s.source.errorf(columnAt(s.columns, offset), format, a...)
+}
+
+// Parse reads a Hack assembly file from r and returns its statements. Filename is used for the
+// positions of the statements and to find included files. Includes, macros, and pseudo-instructions
+// are expanded; statements from an expansion have the position of the line in the macro definition
+// or of the pseudo-instruction.
+//
+// Errors in the program are returned as an ErrorList, along with the statements that could be
+// parsed.
+func Parse(filename string, r io.Reader) ([]Statement, error) {
+	lines, err := readLines(filename, r)
+	if err != nil {
+		return nil, err
+	}
+	pp := newPreprocessor(Options{}.open)
+	lines = pp.file(filename, lines)
+	statements, errs := parse(lines)
+	errs = append(pp.errs, errs...)
+	errs.Sort()
+	return statements, errs.Err()
+}
+
+// parse parses preprocessed lines into statements. Lines that can't be parsed are left out and
+// reported in the returned ErrorList.
+func parse(lines []sourceLine) ([]Statement, ErrorList) {
+	var statements []Statement
+	var errs ErrorList
+	p := newLineParser(lines)
+	for p.Scan() {
+		var instruction Instruction
+		var err error
+		switch p.InstructionType() {
+		case TypeASymbolic:
+			instruction, err = p.SymbolicAInstruction()
+		case TypeADecimal:
+			instruction, err = p.DecimalAInstruction()
+		case TypeC:
+			instruction, err = p.CInstruction()
+		case TypeL:
+			instruction, err = p.LInstruction()
+		case TypeEqu:
+			instruction, err = p.EquDirective()
+		case TypeGlobal:
+			instruction, err = p.GlobalDirective()
+		case TypeExtern:
+			instruction, err = p.ExternDirective()
+		}
+		if err != nil {
+			errs = append(errs, err.(*Error))
+			continue
+		}
+		statements = append(statements, Statement{
+			Pos:         p.Pos(),
+			Instruction: instruction,
+			line:        p.index,
+			source:      p.source(),
+			text:        p.current,
+			columns:     p.columns,
+		})
+	}
+	return statements, errs
+}
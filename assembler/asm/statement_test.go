@@ -0,0 +1,37 @@
+package asm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	source := "// comment\n(loop)\n  @loop\n\t@0x10\nD=M;JGT\n.equ N 3\n@99999\n"
+	statements, errs := parse(sourceLines(t, source))
+	var got []any
+	var lines, columns []int
+	for _, s := range statements {
+		got = append(got, s.Instruction)
+		lines = append(lines, s.line)
+		columns = append(columns, s.Pos.Column)
+	}
+	want := []any{
+		LInstruction{Symbol: "loop"},
+		SymbolicAInstruction{Symbol: "loop"},
+		DecimalAInstruction{Value: 16},
+		CInstruction{Dest: "D", Comp: "M", Jump: "JGT"},
+		EquDirective{Symbol: "N", Value: "3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parse returned:\n%#v\nwant:\n%#v", got, want)
+	}
+	if want := []int{2, 3, 4, 5, 6}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("parse returned statements on lines %v, want %v", lines, want)
+	}
+	if want := []int{1, 3, 2, 1, 1}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("parse returned statements in columns %v, want %v", columns, want)
+	}
+	if len(errs) != 1 || errs[0].Pos.Line != 7 {
+		t.Errorf("parse returned errors %v, want one error on line 7", errs)
+	}
+}
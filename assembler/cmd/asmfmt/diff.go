@@ -0,0 +1,221 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// contextLines is the number of unchanged lines shown around each change in a diff.
+	contextLines = 3
+
+	// maxCost bounds the search for a shortest edit script; see middle.
+	maxCost = 1024
+)
+
+// An edit is one line of a diff: an unchanged line (' '), a removed line ('-'), or an added line
+// ('+').
+type edit struct {
+	op   byte
+	text string
+}
+
+// diff returns a unified diff between old and new, or "" if they're the same. Name is used in the
+// header.
+func diff(name, old, new string) string {
+	if old == new {
+		return ""
+	}
+	edits := diffLines(splitLines(old), splitLines(new))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s.orig\n+++ %s\n", name, name)
+
+	// oldLines[i] and newLines[i] are the line numbers in old and new at edits[i]
+	oldLines := make([]int, len(edits)+1)
+	newLines := make([]int, len(edits)+1)
+	oldLines[0], newLines[0] = 1, 1
+	for i, e := range edits {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if e.op != '+' {
+			oldLines[i+1]++
+		}
+		if e.op != '-' {
+			newLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+		// a hunk includes all changes that are separated by at most twice the context lines
+		last := i
+		for j := i + 1; j < len(edits) && j <= last+2*contextLines+1; j++ {
+			if edits[j].op != ' ' {
+				last = j
+			}
+		}
+		start := max(0, i-contextLines)
+		end := min(len(edits), last+contextLines+1)
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldLines[start], oldLines[end]-oldLines[start],
+			newLines[start], newLines[end]-newLines[start])
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(&b, "%c%s\n", e.op, e.text)
+		}
+		i = end
+	}
+	return b.String()
+}
+
+func splitLines(s string) []string {
+	lines := strings.Split(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns a list of edits that turns a into b, usually the shortest one. It uses Myers'
+// algorithm in its linear-space form: find the middle of a shortest edit script, then diff the
+// parts before and after it, so even large files only need memory proportional to their length.
+func diffLines(a, b []string) []edit {
+	var edits []edit
+	replace := func(a, b []string) {
+		for _, line := range a {
+			edits = append(edits, edit{'-', line})
+		}
+		for _, line := range b {
+			edits = append(edits, edit{'+', line})
+		}
+	}
+	var walk func(a, b []string)
+	walk = func(a, b []string) {
+		// common lines at the start and end need no search
+		prefix := 0
+		for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+			prefix++
+		}
+		for _, line := range a[:prefix] {
+			edits = append(edits, edit{' ', line})
+		}
+		a, b = a[prefix:], b[prefix:]
+		suffix := 0
+		for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+			suffix++
+		}
+		common := a[len(a)-suffix:]
+		a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+		if len(a) == 0 || len(b) == 0 {
+			replace(a, b)
+		} else if x, y, ok := middle(a, b); ok {
+			walk(a[:x], b[:y])
+			walk(a[x:], b[y:])
+		} else {
+			replace(a, b)
+		}
+		for _, line := range common {
+			edits = append(edits, edit{' ', line})
+		}
+	}
+	walk(a, b)
+
+	// within each change, list the removed lines before the added ones
+	for i := 0; i < len(edits); {
+		j := i
+		for j < len(edits) && edits[j].op != ' ' {
+			j++
+		}
+		slices.SortStableFunc(edits[i:j], func(e, f edit) int { return cmp.Compare(f.op, e.op) })
+		i = j + 1
+	}
+	return edits
+}
+
+// middle returns a point (x, y) on a shortest edit script from a to b that lies about halfway
+// through it, found by searching forward from the start and backward from the end at the same
+// time until the two searches meet. If a and b have no lines in common, it returns false.
+//
+// The search takes time proportional to the square of the number of edits, which is too slow for
+// files where almost every line changes, like a generated file that was never formatted. So, like
+// GNU diff, it gives up after maxCost steps and splits where the forward search got furthest; the
+// result is still correct, just not always the shortest.
+func middle(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[offset+k] is the furthest x reached on diagonal k = x - y searching from the start,
+	// and backward[offset+k] the same searching from the end, counting from the end; -1 means
+	// not reached yet
+	forward := make([]int, 2*maxD+1)
+	backward := make([]int, 2*maxD+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	odd := delta%2 != 0
+	// diagonals that have left the edit graph, on either side, aren't searched anymore
+	var forwardStart, forwardEnd, backwardStart, backwardEnd int
+	for d := range min(maxD, maxCost) {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch r := offset + delta - k; {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case odd && r >= 0 && r < len(backward) && backward[r] != -1 && x >= n-backward[r]:
+				return x, y, true
+			}
+		}
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			var u int
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				u = backward[offset+k+1]
+			} else {
+				u = backward[offset+k-1] + 1
+			}
+			v := u - k
+			for u < n && v < m && a[n-1-u] == b[m-1-v] {
+				u++
+				v++
+			}
+			backward[offset+k] = u
+			switch f := offset + delta - k; {
+			case u > n:
+				backwardEnd += 2
+			case v > m:
+				backwardStart += 2
+			case !odd && f >= 0 && f < len(forward) && forward[f] != -1 && forward[f] >= n-u:
+				x = forward[f]
+				return x, x - (f - offset), true
+			}
+		}
+	}
+	if maxD <= maxCost {
+		return 0, 0, false
+	}
+
+	best := 0
+	for i, fx := range forward {
+		if fy := fx - (i - offset); fx >= 0 && fx <= n && fy >= 0 && fy <= m && fx+fy > best {
+			x, y, best = fx, fy, fx+fy
+		}
+	}
+	return x, y, best > 0 && best < n+m
+}
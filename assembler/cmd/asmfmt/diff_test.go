@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	want := `--- x.asm.orig
++++ x.asm
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if got := diff("x.asm", old, new); got != want {
+		t.Errorf("diff returned:\n%s\nwant:\n%s", got, want)
+	}
+	if got := diff("x.asm", old, old); got != "" {
+		t.Errorf("diff of equal texts returned:\n%s", got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// random texts from a small alphabet have many common lines, so the edits are checked against
+	// the length of a longest common subsequence computed the simple way
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(3)))
+		}
+		return lines
+	}
+	for range 1000 {
+		a, b := random(), random()
+		edits := diffLines(a, b)
+		var gotA, gotB []string
+		changes := 0
+		for _, e := range edits {
+			if e.op != '+' {
+				gotA = append(gotA, e.text)
+			}
+			if e.op != '-' {
+				gotB = append(gotB, e.text)
+			}
+			if e.op != ' ' {
+				changes++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+			t.Fatalf("diffLines(%q, %q) returned edits for %q and %q", a, b, gotA, gotB)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); changes != want {
+			t.Fatalf("diffLines(%q, %q) returned %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+func TestDiffLargeFile(t *testing.T) {
+	// every line changes, which would need a table with n² entries for a quadratic-space diff
+	var old, new strings.Builder
+	for i := range 30000 {
+		old.WriteString(strings.Repeat("x", i%7) + "\n")
+		new.WriteString("    " + strings.Repeat("x", i%7) + "\n")
+	}
+	if diff("x.asm", old.String(), new.String()) == "" {
+		t.Errorf("diff of different texts returned nothing")
+	}
+}
+
+func lcsLength(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs[0][0]
+}
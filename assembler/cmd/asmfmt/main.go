@@ -0,0 +1,105 @@
+/*
+Asmfmt formats Hack assembly programs in the canonical style: labels and directives at the start
+of the line, instructions indented by four spaces, dest fields in the conventional order (MD
+rather than DM), aligned trailing comments, and no redundant blank lines.
+
+Usage:
+
+	asmfmt [flags] [file.asm ...]
+
+Without flags, it writes the formatted files to standard output. Without file names, it formats
+standard input.
+
+Flags:
+
+	-d  print a diff for each file whose formatting differs from asmfmt's
+	-l  list the files whose formatting differs from asmfmt's
+	-w  write the result back to the file instead of to standard output
+
+With -d or -l, asmfmt exits with status 1 if any file isn't formatted, so it can be used in
+pre-commit checks.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lfritz/nand2tetris/assembler/asm"
+)
+
+var (
+	diffMode  = flag.Bool("d", false, "print diffs")
+	listMode  = flag.Bool("l", false, "list files whose formatting differs")
+	writeMode = flag.Bool("w", false, "write the result back to the file")
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	unformatted := false
+	if flag.NArg() == 0 {
+		if *writeMode {
+			errorAndExit("error: can't use -w on standard input")
+		}
+		src, err := io.ReadAll(os.Stdin)
+		check(err)
+		unformatted = process("<standard input>", src)
+	}
+	for _, path := range flag.Args() {
+		src, err := os.ReadFile(path)
+		check(err)
+		if process(path, src) {
+			unformatted = true
+		}
+	}
+	if unformatted && (*diffMode || *listMode) {
+		os.Exit(1)
+	}
+}
+
+// process formats one file and reports whether its formatting changed.
+func process(path string, src []byte) bool {
+	formatted := asm.FormatSource(src)
+	changed := !bytes.Equal(src, formatted)
+	if *listMode && changed {
+		fmt.Println(path)
+	}
+	if *diffMode && changed {
+		fmt.Print(diff(path, string(src), string(formatted)))
+	}
+	if *writeMode && changed {
+		check(os.WriteFile(path, formatted, 0644))
+	}
+	if !*listMode && !*diffMode && !*writeMode {
+		_, err := os.Stdout.Write(formatted)
+		check(err)
+	}
+	return changed
+}
+
+func check(err error) {
+	if err == nil {
+		return
+	}
+	errorAndExit("error: %v", err)
+}
+
+func errorAndExit(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	fmt.Fprintln(os.Stderr)
+	os.Exit(1)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "    asmfmt [flags] [file.asm ...]")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fmt.Fprintln(os.Stderr, "    -d  print a diff for each file whose formatting differs")
+	fmt.Fprintln(os.Stderr, "    -l  list the files whose formatting differs")
+	fmt.Fprintln(os.Stderr, "    -w  write the result back to the file")
+}
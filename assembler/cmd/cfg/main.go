@@ -0,0 +1,98 @@
+/*
+The cfg command builds the control-flow graph of a Hack assembly program and writes it in Graphviz
+DOT format or as JSON.
+
+Usage:
+
+	cfg [flags] program.asm
+
+This will write the graph to standard output. Basic blocks that can't be reached from address 0 are
+shaded in the DOT output and marked as unreachable in JSON. Jumps whose target isn't known, such as
+"A=M;JMP", are shown as indirect edges to every block whose address the program loads.
+
+Flags:
+
+	-json    write JSON instead of DOT
+	-o file  write the graph to file instead
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lfritz/nand2tetris/assembler/asm"
+)
+
+func main() {
+	// check command-line arguments
+	jsonOutput := flag.Bool("json", false, "write JSON")
+	outPath := flag.String("o", "", "output file")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(1)
+	}
+	inPath := flag.Arg(0)
+
+	// parse the program and build the graph
+	statements, err := parse(inPath)
+	if err != nil {
+		var errs asm.ErrorList
+		if errors.As(err, &errs) {
+			errorAndExit("%v", errs)
+		}
+		check(err)
+	}
+	g, err := asm.BuildCFG(statements)
+	check(err)
+
+	// write it
+	var w io.Writer = os.Stdout
+	if *outPath != "" {
+		outFile, err := os.Create(*outPath)
+		check(err)
+		defer outFile.Close()
+		w = outFile
+	}
+	if *jsonOutput {
+		err = g.WriteJSON(w)
+	} else {
+		err = g.WriteDOT(w)
+	}
+	check(err)
+}
+
+func parse(path string) ([]asm.Statement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return asm.Parse(path, f)
+}
+
+func check(err error) {
+	if err == nil {
+		return
+	}
+	errorAndExit("error: %v", err)
+}
+
+func errorAndExit(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	fmt.Fprintln(os.Stderr)
+	os.Exit(1)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "    cfg [flags] program.asm")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fmt.Fprintln(os.Stderr, "    -json    write JSON instead of Graphviz DOT")
+	fmt.Fprintln(os.Stderr, "    -o file  write the graph to file")
+}
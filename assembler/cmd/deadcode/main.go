@@ -0,0 +1,75 @@
+/*
+The deadcode command reports code in Hack assembly programs that has no effect: instructions that
+can't be reached, values loaded into A or D that are never read, and stores to variables that are
+never read.
+
+Usage:
+
+	deadcode program.asm ...
+
+Each finding is printed with its position. The exit status is 1 if anything was found, so it can
+be used in scripts, for example to check the output of a compiler.
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lfritz/nand2tetris/assembler/asm"
+)
+
+func main() {
+	// check command-line arguments
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	found := false
+	for _, path := range flag.Args() {
+		statements, err := parse(path)
+		if err == nil {
+			var findings asm.ErrorList
+			findings, err = asm.DeadCode(statements)
+			if len(findings) > 0 {
+				fmt.Println(findings)
+				found = true
+			}
+		}
+		if err != nil {
+			var errs asm.ErrorList
+			if errors.As(err, &errs) {
+				errorAndExit("%v", errs)
+			}
+			errorAndExit("error: %v", err)
+		}
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+func parse(path string) ([]asm.Statement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return asm.Parse(path, f)
+}
+
+func errorAndExit(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	fmt.Fprintln(os.Stderr)
+	os.Exit(1)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "    deadcode program.asm ...")
+}
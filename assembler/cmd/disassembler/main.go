@@ -0,0 +1,68 @@
+/*
+The disassembler translates binary Hack programs (.hack files) back into Hack assembly.
+
+Usage:
+
+	disassembler [flags] program.hack
+
+This will read program.hack and write assembly code to standard output.
+
+Flags:
+
+	-labels  declare labels for jump targets and use them in A-instructions
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lfritz/nand2tetris/assembler/asm"
+)
+
+func main() {
+	// check command-line arguments
+	labels := flag.Bool("labels", false, "declare labels for jump targets")
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+	if len(args) != 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	// open input file
+	inFile, err := os.Open(args[0])
+	check(err)
+	defer inFile.Close()
+
+	// run the disassembler
+	err = asm.Disassemble(args[0], inFile, os.Stdout, *labels)
+	var errs asm.ErrorList
+	if errors.As(err, &errs) {
+		errorAndExit("%v", errs)
+	}
+	check(err)
+}
+
+func check(err error) {
+	if err == nil {
+		return
+	}
+	errorAndExit("error: %v", err)
+}
+
+func errorAndExit(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	fmt.Fprintln(os.Stderr)
+	os.Exit(1)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "    disassembler [flags] program.hack")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fmt.Fprintln(os.Stderr, "    -labels  declare labels for jump targets")
+}
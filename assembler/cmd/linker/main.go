@@ -0,0 +1,101 @@
+/*
+The linker combines relocatable object files (.obj files, written by "assembler -c") into a binary
+Hack program.
+
+Usage:
+
+	linker [flags] main.obj more.obj ...
+
+This will write the program to main.hack. Object files are placed in ROM in the order given.
+Labels exported with .global can be used in all object files, and variables are allocated for the
+whole program starting at address 16.
+
+Flags:
+
+	-f format  write the program in the given format (see the assembler)
+	-o file    write the program to file instead
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lfritz/nand2tetris/assembler/asm"
+)
+
+func main() {
+	// check command-line arguments
+	outPath := flag.String("o", "", "output file")
+	formatName := flag.String("f", "hack", "output format")
+	flag.Usage = usage
+	flag.Parse()
+	inPaths := flag.Args()
+	if len(inPaths) == 0 {
+		usage()
+		os.Exit(1)
+	}
+	format, err := asm.ParseFormat(*formatName)
+	check(err)
+	if *outPath == "" {
+		*outPath = strings.TrimSuffix(inPaths[0], filepath.Ext(inPaths[0])) + format.Extension()
+	}
+
+	// read object files
+	var objects []*asm.Object
+	for _, inPath := range inPaths {
+		object, err := readObject(inPath)
+		check(err)
+		objects = append(objects, object)
+	}
+
+	// link them
+	outFile, err := os.Create(*outPath)
+	check(err)
+	defer outFile.Close()
+	err = asm.Link(objects, outFile, format)
+	if err != nil {
+		// don't leave a partial output file behind
+		outFile.Close()
+		os.Remove(*outPath)
+		var errs asm.ErrorList
+		if errors.As(err, &errs) {
+			errorAndExit("%v", errs)
+		}
+		check(err)
+	}
+}
+
+func readObject(path string) (*asm.Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return asm.ReadObject(path, f)
+}
+
+func check(err error) {
+	if err == nil {
+		return
+	}
+	errorAndExit("error: %v", err)
+}
+
+func errorAndExit(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	fmt.Fprintln(os.Stderr)
+	os.Exit(1)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "    linker [flags] main.obj more.obj ...")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fmt.Fprintln(os.Stderr, "    -f format  output format: hack, raw, ihex, readmemb, readmemh, or logisim")
+	fmt.Fprintln(os.Stderr, "    -o file    write the program to file")
+}
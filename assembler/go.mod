@@ -1,3 +1,7 @@
 module github.com/lfritz/nand2tetris/assembler
 
 go 1.24.1
+
+require github.com/lfritz/nand2tetris/hackrun v0.0.0
+
+replace github.com/lfritz/nand2tetris/hackrun => ../hackrun
@@ -4,47 +4,148 @@ files).
 
 Usage:
 
-	assembler program.asm
+	assembler [flags] program.asm [more.asm ...]
 
-This will read program.asm and write the binary program to program.hack.
+This will read program.asm and write the binary program to program.hack. If there are several
+input files, they're combined into one program, and the output file is named after the first one.
+
+An input file named "-" means standard input; the output then goes to standard output unless -o is
+given. "-o -" writes to standard output, so the assembler can be used in a pipeline:
+
+	translator prog.vm | assembler - > prog.hack
+
+Flags:
+
+	-O level   optimize the program: 1 removes redundant instructions in straight-line code, 2
+	           also removes unreachable code after unconditional jumps, 3 also replaces repeated
+	           sequences of instructions with calls to shared subroutines
+	-c         write a relocatable object file (program.obj) for the linker instead
+	-f format  write the binary program in the given format instead of the .hack text format
+	-l         also write a listing with the ROM address and binary code of each line to program.lst
+	-o file    write the binary program to file instead
+	-stats     with -O, print how many instructions each optimization rule saved
+	-strict    treat warnings as errors
+	-varlimit n
+	           warn about variables allocated past RAM address n (default 255)
+
+The formats are hack (the default), raw (16-bit big-endian words, .bin), ihex (Intel HEX, .hex),
+readmemb and readmemh (memory files for Verilog's $readmemb and $readmemh, .mem), and logisim (a
+Logisim ROM image, .img). Without -o, the output file gets the format's usual extension.
 */
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
-	"github.com/lfritz/nand2tetris/assembler/internal"
+	"github.com/lfritz/nand2tetris/assembler/asm"
 )
 
 func main() {
-	// check command-line argument
-	args := os.Args[1:]
-	if len(args) != 1 {
-		errorAndExit("Usage: assembler input.asm")
+	// check command-line arguments
+	listing := flag.Bool("l", false, "also write a listing to a .lst file")
+	outPath := flag.String("o", "", "output file")
+	formatName := flag.String("f", "hack", "output format")
+	object := flag.Bool("c", false, "write a relocatable object file")
+	optimize := flag.Int("O", asm.OptimizeNone, "optimization level (0 to 3)")
+	stats := flag.Bool("stats", false, "print how many instructions each optimization rule saved")
+	strict := flag.Bool("strict", false, "treat warnings as errors")
+	variableLimit := flag.Uint("varlimit", asm.DefaultVariableLimit, "highest RAM address for variables")
+	flag.Usage = usage
+	flag.Parse()
+	inPaths := flag.Args()
+	if len(inPaths) == 0 {
+		usage()
+		os.Exit(1)
 	}
 
-	// figure out input and output file names
-	inPath := args[0]
-	if !strings.HasSuffix(inPath, ".asm") {
-		errorAndExit("error: input filename must end in .asm")
+	format, err := asm.ParseFormat(*formatName)
+	check(err)
+	extension := format.Extension()
+	if *object {
+		extension = asm.ObjectExtension
+		if *listing {
+			errorAndExit("error: -l can't be used with -c")
+		}
 	}
-	outPath := strings.TrimSuffix(inPath, ".asm") + ".hack"
 
-	// open input file
-	inFile, err := os.Open(inPath)
-	check(err)
-	defer inFile.Close()
+	// figure out output file names
+	stdin := slices.Contains(inPaths, "-")
+	if stdin && len(inPaths) > 1 {
+		errorAndExit("error: standard input (-) can only be used as the only input file")
+	}
+	if *outPath == "" {
+		if stdin {
+			*outPath = "-"
+		} else {
+			*outPath = strings.TrimSuffix(inPaths[0], filepath.Ext(inPaths[0])) + extension
+		}
+	}
+	if *outPath != "-" && slices.Contains(inPaths, *outPath) {
+		errorAndExit("error: output file %s would overwrite an input file", *outPath)
+	}
+	stdout := *outPath == "-"
+	if stdout && *listing {
+		errorAndExit("error: -l needs an output file (use -o)")
+	}
+	listingPath := strings.TrimSuffix(*outPath, filepath.Ext(*outPath)) + ".lst"
 
-	// open output file
-	outFile, err := os.Create(outPath)
-	check(err)
-	defer outFile.Close()
+	// open output files
+	outFile := os.Stdout
+	if !stdout {
+		outFile, err = os.Create(*outPath)
+		check(err)
+		defer outFile.Close()
+	}
+	options := asm.Options{
+		Format:        format,
+		VariableLimit: *variableLimit,
+		Warnings:      os.Stderr,
+		Strict:        *strict,
+		Optimize:      *optimize,
+		Object:        *object,
+	}
+	if *stats {
+		options.Savings = os.Stderr
+	}
+	var listingFile *os.File
+	if *listing {
+		listingFile, err = os.Create(listingPath)
+		check(err)
+		defer listingFile.Close()
+		options.Listing = listingFile
+	}
 
 	// run the assembler
-	err = internal.Run(inFile, outFile)
-	check(err)
+	if stdin {
+		err = asm.Run("<stdin>", os.Stdin, outFile, options)
+	} else {
+		err = asm.RunFiles(inPaths, outFile, options)
+	}
+	if err != nil {
+		// don't leave partial output files behind
+		if !stdout {
+			removeOutput(outFile, *outPath)
+		}
+		if listingFile != nil {
+			removeOutput(listingFile, listingPath)
+		}
+		var errs asm.ErrorList
+		if errors.As(err, &errs) {
+			errorAndExit("%v", errs)
+		}
+		check(err)
+	}
+}
+
+func removeOutput(f *os.File, path string) {
+	f.Close()
+	os.Remove(path)
 }
 
 func check(err error) {
@@ -59,3 +160,19 @@ func errorAndExit(format string, a ...any) {
 	fmt.Fprintln(os.Stderr)
 	os.Exit(1)
 }
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "    assembler [flags] program.asm [more.asm ...]")
+	fmt.Fprintln(os.Stderr, "    assembler [flags] - < program.asm > program.hack")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fmt.Fprintln(os.Stderr, "    -O level   optimization level: 0 (none), 1 (local), 2 (also unreachable code), or 3 (also outlining)")
+	fmt.Fprintln(os.Stderr, "    -c         write a relocatable object file for the linker")
+	fmt.Fprintln(os.Stderr, "    -f format  output format: hack, raw, ihex, readmemb, readmemh, or logisim")
+	fmt.Fprintln(os.Stderr, "    -l         also write a listing to program.lst")
+	fmt.Fprintln(os.Stderr, "    -o file    write the binary program to file (- for standard output)")
+	fmt.Fprintln(os.Stderr, "    -stats     print how many instructions each optimization rule saved")
+	fmt.Fprintln(os.Stderr, "    -strict    treat warnings as errors")
+	fmt.Fprintln(os.Stderr, "    -varlimit n")
+	fmt.Fprintln(os.Stderr, "               warn about variables allocated past RAM address n (default 255)")
+}
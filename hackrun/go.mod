@@ -0,0 +1,3 @@
+module github.com/lfritz/nand2tetris/hackrun
+
+go 1.24.1
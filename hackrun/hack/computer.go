@@ -0,0 +1,183 @@
+// Package hack emulates the Hack computer. Besides hackrun, tests for the other tools use it to run
+// the programs they produce.
+package hack
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// MemorySize is the number of 16-bit words in both ROM and RAM.
+	MemorySize = 32768
+
+	// Screen is the base address of the memory-mapped screen.
+	Screen = 16384
+
+	// Keyboard is the address of the memory-mapped keyboard.
+	Keyboard = 24576
+)
+
+// A Computer emulates the Hack computer: a CPU with registers A, D, and PC, an instruction memory
+// (ROM), and a data memory (RAM) with the screen and keyboard mapped into it.
+type Computer struct {
+	ROM [MemorySize]uint16
+	RAM [MemorySize]uint16
+
+	A, D, PC uint16
+
+	// Cycles counts the number of instructions executed so far.
+	Cycles int
+}
+
+// NewComputer returns a Computer with the given program loaded into ROM.
+func NewComputer(program []uint16) (*Computer, error) {
+	if len(program) > MemorySize {
+		return nil, fmt.Errorf("program too large: %d instructions, ROM holds %d", len(program), MemorySize)
+	}
+	c := &Computer{}
+	copy(c.ROM[:], program)
+	return c, nil
+}
+
+// Reset sets the program counter to 0, like the reset input of the Hack CPU.
+func (c *Computer) Reset() {
+	c.PC = 0
+}
+
+// SetKey sets the key code of the currently pressed key, or 0 for no key.
+func (c *Computer) SetKey(code uint16) {
+	c.RAM[Keyboard] = code
+}
+
+// Step executes one instruction.
+func (c *Computer) Step() {
+	instruction := c.ROM[c.PC%MemorySize]
+	c.Cycles++
+
+	// A-instruction
+	if instruction&0x8000 == 0 {
+		c.A = instruction
+		c.PC++
+		return
+	}
+
+	// C-instruction: 111a cccc ccdd djjj
+	y := c.A
+	if instruction&0x1000 != 0 {
+		y = c.RAM[c.A%MemorySize]
+	}
+	out := alu(c.D, y, instruction>>6)
+
+	// The write to M goes to the address A had before this instruction, the jump goes to the value
+	// A has after it; this matches the CPU emulator that comes with the course.
+	if instruction&0x08 != 0 {
+		c.write(c.A, out)
+	}
+	if instruction&0x20 != 0 {
+		c.A = out
+	}
+	if instruction&0x10 != 0 {
+		c.D = out
+	}
+	if jump(out, instruction) {
+		c.PC = c.A
+	} else {
+		c.PC++
+	}
+}
+
+// Run executes up to n instructions. It stops early if the program reaches the idiomatic infinite
+// loop at the end of a Hack program. It returns the number of instructions executed.
+func (c *Computer) Run(n int) int {
+	for i := 0; i < n; i++ {
+		if c.Halted() {
+			return i
+		}
+		c.Step()
+	}
+	return n
+}
+
+// Halted reports whether the computer is stuck in an infinite loop of the form
+//
+//	(END)
+//	@END
+//	0;JMP
+//
+// or an unconditional jump to itself.
+func (c *Computer) Halted() bool {
+	instruction := c.ROM[c.PC%MemorySize]
+	if instruction&0xe007 != 0xe007 || instruction&0x38 != 0 {
+		// not an unconditional jump that leaves all registers alone
+		return false
+	}
+	if c.A == c.PC {
+		return true
+	}
+	return c.PC > 0 && c.A == c.PC-1 && c.ROM[c.A%MemorySize] == c.A
+}
+
+func (c *Computer) write(address, value uint16) {
+	address %= MemorySize
+	if address == Keyboard {
+		// the keyboard register is read-only
+		return
+	}
+	c.RAM[address] = value
+}
+
+// alu computes the output of the Hack ALU for inputs x and y, given the six control bits zx, nx, zy,
+// ny, f, no in the lowest bits of control.
+func alu(x, y uint16, control uint16) uint16 {
+	if control&0x20 != 0 { // zx
+		x = 0
+	}
+	if control&0x10 != 0 { // nx
+		x = ^x
+	}
+	if control&0x08 != 0 { // zy
+		y = 0
+	}
+	if control&0x04 != 0 { // ny
+		y = ^y
+	}
+	var out uint16
+	if control&0x02 != 0 { // f
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if control&0x01 != 0 { // no
+		out = ^out
+	}
+	return out
+}
+
+// jump evaluates the jump bits of a C-instruction for the given ALU output.
+func jump(out uint16, instruction uint16) bool {
+	value := int16(out)
+	switch {
+	case value < 0:
+		return instruction&0x04 != 0
+	case value == 0:
+		return instruction&0x02 != 0
+	default:
+		return instruction&0x01 != 0
+	}
+}
+
+// DumpRAM writes the contents of RAM addresses from to to (inclusive) to w, one word per line, as
+// signed decimal numbers.
+func (c *Computer) DumpRAM(w io.Writer, from, to int) error {
+	if from < 0 || to >= MemorySize || from > to {
+		return fmt.Errorf("invalid RAM range: %d-%d", from, to)
+	}
+	for address := from; address <= to; address++ {
+		_, err := fmt.Fprintf(w, "RAM[%d] = %d\n", address, int16(c.RAM[address]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package hack
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestALU(t *testing.T) {
+	// the comp codes from the course, without the "a" bit
+	x, y := uint16(0x1234), uint16(0x0f0f)
+	cases := []struct {
+		comp    string
+		control uint16
+		want    uint16
+	}{
+		{"0", 0b101010, 0},
+		{"1", 0b111111, 1},
+		{"-1", 0b111010, 0xffff},
+		{"D", 0b001100, x},
+		{"A", 0b110000, y},
+		{"!D", 0b001101, ^x},
+		{"!A", 0b110001, ^y},
+		{"-D", 0b001111, -x},
+		{"-A", 0b110011, -y},
+		{"D+1", 0b011111, x + 1},
+		{"A+1", 0b110111, y + 1},
+		{"D-1", 0b001110, x - 1},
+		{"A-1", 0b110010, y - 1},
+		{"D+A", 0b000010, x + y},
+		{"D-A", 0b010011, x - y},
+		{"A-D", 0b000111, y - x},
+		{"D&A", 0b000000, x & y},
+		{"D|A", 0b010101, x | y},
+	}
+	for _, c := range cases {
+		got := alu(x, y, c.control)
+		if got != c.want {
+			t.Errorf("alu for %q returned %#04x, want %#04x", c.comp, got, c.want)
+		}
+	}
+}
+
+func TestStep(t *testing.T) {
+	program := []uint16{
+		0b0000000000000111, // @7
+		0b1110110000010000, // D=A
+		0b1110011111001000, // M=D+1
+		0b1111110111101000, // AM=M+1
+		0b1110001100000011, // D;JGE
+	}
+	c, err := NewComputer(program)
+	if err != nil {
+		t.Fatalf("NewComputer returned error: %v", err)
+	}
+	c.Run(4)
+	if c.A != 9 || c.D != 7 || c.RAM[7] != 9 || c.PC != 4 {
+		t.Errorf("after 4 steps got A=%d, D=%d, RAM[7]=%d, PC=%d, want A=9, D=7, RAM[7]=9, PC=4",
+			c.A, c.D, c.RAM[7], c.PC)
+	}
+	c.Step()
+	if c.PC != 9 {
+		t.Errorf("after jump got PC=%d, want 9", c.PC)
+	}
+}
+
+func TestRunMax(t *testing.T) {
+	// samples/max.hack: computes R2 = max(R0, R1)
+	source := `
+		0000000000000000
+		1111110000010000
+		0000000000000001
+		1111010011010000
+		0000000000001010
+		1110001100000001
+		0000000000000001
+		1111110000010000
+		0000000000001100
+		1110101010000111
+		0000000000000000
+		1111110000010000
+		0000000000000010
+		1110001100001000
+		0000000000001110
+		1110101010000111
+	`
+	var program []uint16
+	for _, field := range strings.Fields(source) {
+		word, err := strconv.ParseUint(field, 2, 16)
+		if err != nil {
+			t.Fatalf("invalid instruction %q", field)
+		}
+		program = append(program, uint16(word))
+	}
+	cases := []struct {
+		r0, r1, want int16
+	}{
+		{3, 5, 5},
+		{5, 3, 5},
+		{-4, -9, -4},
+	}
+	for _, c := range cases {
+		computer, err := NewComputer(program)
+		if err != nil {
+			t.Fatalf("NewComputer returned error: %v", err)
+		}
+		computer.RAM[0] = uint16(c.r0)
+		computer.RAM[1] = uint16(c.r1)
+		executed := computer.Run(1000)
+		if executed == 1000 {
+			t.Errorf("max(%d, %d) did not halt", c.r0, c.r1)
+		}
+		got := int16(computer.RAM[2])
+		if got != c.want {
+			t.Errorf("max(%d, %d) returned %d, want %d", c.r0, c.r1, got, c.want)
+		}
+	}
+}
+
+func TestKeyboardIsReadOnly(t *testing.T) {
+	program := []uint16{
+		Keyboard,           // @KBD
+		0b1110111111001000, // M=1
+	}
+	c, err := NewComputer(program)
+	if err != nil {
+		t.Fatalf("NewComputer returned error: %v", err)
+	}
+	c.SetKey(65)
+	c.Run(2)
+	if c.RAM[Keyboard] != 65 {
+		t.Errorf("got RAM[KBD]=%d after write, want 65", c.RAM[Keyboard])
+	}
+}
+
+func TestDumpRAM(t *testing.T) {
+	c, err := NewComputer(nil)
+	if err != nil {
+		t.Fatalf("NewComputer returned error: %v", err)
+	}
+	c.RAM[256] = 7
+	c.RAM[257] = 0xffff
+	var builder strings.Builder
+	err = c.DumpRAM(&builder, 256, 257)
+	if err != nil {
+		t.Fatalf("DumpRAM returned error: %v", err)
+	}
+	want := "RAM[256] = 7\nRAM[257] = -1\n"
+	got := builder.String()
+	if got != want {
+		t.Errorf("DumpRAM produced:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHaltedAddressPastROM(t *testing.T) {
+	// jumps to 32776, which wraps around to address 8. The word there is 32776, which runs as
+	// M=D&A and leaves A alone, so the jump at 9 loops back to it forever.
+	program := []uint16{
+		32767,              // @32767
+		0b1110110000010000, // D=A
+		9,                  // @9
+		0b1110000010100000, // A=D+A
+		0b1110101010000111, // 0;JMP
+		0,                  // @0
+		0,                  // @0
+		0,                  // @0
+		32776,              // M=D&A
+		0b1110101010000111, // 0;JMP
+	}
+	c, err := NewComputer(program)
+	if err != nil {
+		t.Fatalf("NewComputer returned error: %v", err)
+	}
+	if steps := c.Run(20); steps != 6 {
+		t.Errorf("Run returned %d, want 6", steps)
+	}
+	if !c.Halted() {
+		t.Errorf("Halted returned false at PC %d with A %d", c.PC, c.A)
+	}
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lfritz/nand2tetris/hackrun/hack"
+)
+
+// Load reads a binary Hack program (a .hack file) from r. Each line of the file must contain one
+// instruction written as 16 '0' and '1' characters; blank lines are ignored.
+func Load(r io.Reader) ([]uint16, error) {
+	var program []uint16
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		instruction, err := parseInstruction(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		if len(program) == hack.MemorySize {
+			return nil, fmt.Errorf("line %d: program too large, ROM holds %d instructions", lineNumber, hack.MemorySize)
+		}
+		program = append(program, instruction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// LoadRaw reads a binary Hack program in the raw format written by the assembler's -f raw option:
+// each instruction as a 16-bit big-endian word.
+func LoadRaw(r io.Reader) ([]uint16, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("raw program has odd length (%d bytes)", len(data))
+	}
+	if len(data)/2 > hack.MemorySize {
+		return nil, fmt.Errorf("program too large, ROM holds %d instructions", hack.MemorySize)
+	}
+	program := make([]uint16, len(data)/2)
+	for i := range program {
+		program[i] = binary.BigEndian.Uint16(data[2*i:])
+	}
+	return program, nil
+}
+
+func parseInstruction(line string) (uint16, error) {
+	if len(line) != 16 {
+		return 0, fmt.Errorf("invalid instruction (expected 16 bits): %q", line)
+	}
+	var instruction uint16
+	for _, c := range line {
+		instruction <<= 1
+		switch c {
+		case '0':
+		case '1':
+			instruction |= 1
+		default:
+			return 0, fmt.Errorf("invalid instruction (expected only 0 and 1): %q", line)
+		}
+	}
+	return instruction, nil
+}
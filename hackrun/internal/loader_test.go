@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	source := "0000000000000010\n\n1110110000010000\n"
+	got, err := Load(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := []uint16{2, 0xec10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load returned %#v, want %#v", got, want)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	cases := []string{
+		"000000000000001",
+		"00000000000000102",
+		"@2",
+	}
+	for _, c := range cases {
+		_, err := Load(strings.NewReader(c))
+		if err == nil {
+			t.Errorf("Load(%q) did not return error", c)
+		}
+	}
+}
+
+func TestLoadRaw(t *testing.T) {
+	got, err := LoadRaw(strings.NewReader("\x00\x02\xec\x10"))
+	if err != nil {
+		t.Fatalf("LoadRaw returned error: %v", err)
+	}
+	want := []uint16{2, 0xec10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadRaw returned %#v, want %#v", got, want)
+	}
+	if _, err := LoadRaw(strings.NewReader("\x00\x02\xec")); err == nil {
+		t.Error("LoadRaw did not return error for odd length")
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Hackrun runs binary Hack programs (.hack files) on an emulated Hack computer, without a screen or
+keyboard, and prints the contents of RAM afterwards.
+
+Usage:
+
+	hackrun [flags] program.hack
+
+Files ending in .bin are read as raw 16-bit big-endian words, as written by "assembler -f raw".
+
+Flags:
+
+	-n cycles       run for at most this many cycles (default 1000000)
+	-set addr=value set a RAM address before running; may be repeated
+	-dump from-to   print a RAM range after running; may be repeated (default 0-15)
+
+The program stops early when it reaches an infinite loop of the form "(END) @END 0;JMP".
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lfritz/nand2tetris/hackrun/hack"
+	"github.com/lfritz/nand2tetris/hackrun/internal"
+)
+
+type ramRange struct {
+	from, to int
+}
+
+type ramRanges []ramRange
+
+func (r *ramRanges) String() string {
+	var parts []string
+	for _, rr := range *r {
+		parts = append(parts, fmt.Sprintf("%d-%d", rr.from, rr.to))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *ramRanges) Set(value string) error {
+	fromText, toText, ok := strings.Cut(value, "-")
+	if !ok {
+		toText = fromText
+	}
+	from, err := strconv.Atoi(fromText)
+	if err != nil {
+		return fmt.Errorf("invalid RAM range: %q", value)
+	}
+	to, err := strconv.Atoi(toText)
+	if err != nil {
+		return fmt.Errorf("invalid RAM range: %q", value)
+	}
+	*r = append(*r, ramRange{from, to})
+	return nil
+}
+
+type ramValues map[int]int
+
+func (v ramValues) String() string {
+	return ""
+}
+
+func (v ramValues) Set(value string) error {
+	addressText, valueText, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected addr=value: %q", value)
+	}
+	address, err := strconv.Atoi(addressText)
+	if err != nil || address < 0 || address >= hack.MemorySize {
+		return fmt.Errorf("invalid RAM address: %q", addressText)
+	}
+	n, err := strconv.ParseInt(valueText, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid 16-bit value: %q", valueText)
+	}
+	v[address] = int(n)
+	return nil
+}
+
+func main() {
+	// parse command-line arguments
+	cycles := flag.Int("n", 1000000, "run for at most this many cycles")
+	values := ramValues{}
+	flag.Var(values, "set", "set a RAM address before running (addr=value)")
+	var dumps ramRanges
+	flag.Var(&dumps, "dump", "print a RAM range after running (from-to)")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(1)
+	}
+	if len(dumps) == 0 {
+		dumps = ramRanges{{0, 15}}
+	}
+
+	// load the program
+	inFile, err := os.Open(flag.Arg(0))
+	check(err)
+	defer inFile.Close()
+	load := internal.Load
+	if filepath.Ext(flag.Arg(0)) == ".bin" {
+		load = internal.LoadRaw
+	}
+	program, err := load(inFile)
+	check(err)
+	computer, err := hack.NewComputer(program)
+	check(err)
+	for address, value := range values {
+		computer.RAM[address] = uint16(value)
+	}
+
+	// run it and print the results
+	executed := computer.Run(*cycles)
+	fmt.Printf("executed %d cycles, PC = %d\n", executed, computer.PC)
+	for _, r := range dumps {
+		err := computer.DumpRAM(os.Stdout, r.from, r.to)
+		check(err)
+	}
+}
+
+func check(err error) {
+	if err == nil {
+		return
+	}
+	errorAndExit("error: %v", err)
+}
+
+func errorAndExit(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	fmt.Fprintln(os.Stderr)
+	os.Exit(1)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "    hackrun [flags] program.hack")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
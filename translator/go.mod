@@ -1,3 +1,12 @@
 module github.com/lfritz/nand2tetris/translator
 
 go 1.24.1
+
+require (
+	github.com/lfritz/nand2tetris/assembler v0.0.0
+	github.com/lfritz/nand2tetris/hackrun v0.0.0
+)
+
+replace github.com/lfritz/nand2tetris/assembler => ../assembler
+
+replace github.com/lfritz/nand2tetris/hackrun => ../hackrun
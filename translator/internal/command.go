@@ -62,6 +62,8 @@ func (c CommandType) arity() int {
 		return 2
 	case ReturnCommand:
 		return 0
+	case CallCommand:
+		return 2
 	}
 	return 0
 }
@@ -84,6 +86,8 @@ func commandType(name string) CommandType {
 		return FunctionCommand
 	case "return":
 		return ReturnCommand
+	case "call":
+		return CallCommand
 	}
 	return InvalidCommand
 }
@@ -93,6 +97,9 @@ func commandType(name string) CommandType {
 // For arithmetic-logical commands, Arg1 contains the actual command and Arg2 is empty.
 //
 // For a 'label', 'goto', or 'if-goto' command, Arg1 contains the label and Arg2 is empty.
+//
+// For a 'function' or 'call' command, Arg1 contains the function name and Arg2 the number of local
+// variables or arguments.
 type Command struct {
 	Type       CommandType
 	Arg1, Arg2 string
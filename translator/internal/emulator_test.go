@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/lfritz/nand2tetris/assembler/asm"
+	"github.com/lfritz/nand2tetris/hackrun/hack"
+)
+
+// An emulator runs Hack assembly code, so tests can check what translated programs do. It
+// assembles the code with the assembler and runs it on the same emulated computer as hackrun.
+type emulator struct {
+	*hack.Computer
+	size int // number of instructions in the program
+}
+
+func newEmulator(t *testing.T, code string) *emulator {
+	t.Helper()
+	var output strings.Builder
+	err := asm.Run("test.asm", strings.NewReader(code), &output, asm.Options{Format: asm.FormatRaw})
+	if err != nil {
+		t.Fatalf("assembler returned error: %v", err)
+	}
+	binaryCode := []byte(output.String())
+	program := make([]uint16, len(binaryCode)/2)
+	for i := range program {
+		program[i] = binary.BigEndian.Uint16(binaryCode[2*i:])
+	}
+	c, err := hack.NewComputer(program)
+	if err != nil {
+		t.Fatalf("NewComputer returned error: %v", err)
+	}
+	return &emulator{Computer: c, size: len(program)}
+}
+
+// run executes up to n instructions. It stops early when the program reaches an endless loop like
+// the one at the end of the translator's output, or runs past its last instruction.
+func (e *emulator) run(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n && !e.stopped(); i++ {
+		e.Step()
+	}
+	if !e.stopped() {
+		t.Fatalf("program didn't stop after %d instructions", n)
+	}
+}
+
+func (e *emulator) stopped() bool {
+	return int(e.PC) >= e.size || e.Halted()
+}
@@ -10,6 +10,7 @@ import (
 // Parser implements a parser for the Hack VM language.
 type Parser struct {
 	scanner *bufio.Scanner
+	line    int
 	command *Command
 	err     error
 }
@@ -34,6 +35,7 @@ func (p *Parser) Parse() bool {
 			p.err = p.scanner.Err()
 			return false
 		}
+		p.line++
 		line := p.scanner.Text()
 		p.command, p.err = parseCommand(line)
 		if p.err != nil {
@@ -50,6 +52,12 @@ func (p *Parser) Err() error {
 	return p.err
 }
 
+// Line returns the line number of the command generated by a call to Parse, or of the line that
+// caused an error. Lines are counted from 1.
+func (p *Parser) Line() int {
+	return p.line
+}
+
 // Command returns the command generated by a call to Parse.
 func (p *Parser) Command() Command {
 	return *p.command
@@ -93,6 +101,9 @@ func parseCommand(line string) (*Command, error) {
 	case FunctionCommand:
 		cmd.Arg1 = args[0]
 		cmd.Arg2 = args[1]
+	case CallCommand:
+		cmd.Arg1 = args[0]
+		cmd.Arg2 = args[1]
 	case ReturnCommand:
 	default:
 		return nil, fmt.Errorf("invalid VM command: %q", line)
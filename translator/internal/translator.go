@@ -10,18 +10,68 @@ import (
 // Run runs the translator. It reads and parses Hack VM instructions from r, translates them to Hack
 // assembly code, and writes the result to w.
 func Run(filename string, r io.Reader, w io.Writer) error {
-	t := NewTranslator(NewInstructionWriter(w, filename), filename)
-	parser := NewParser(r)
-	for parser.Parse() {
-		err := t.translate(parser.Command())
-		if err != nil {
-			return err
+	return RunFiles([]File{{filename, r}}, w, Options{})
+}
+
+// A File is a Hack VM file to be translated. Name is the file name without the .vm extension; it's
+// used for the names of the file's static variables.
+type File struct {
+	Name   string
+	Reader io.Reader
+}
+
+// Options control how the translator runs.
+type Options struct {
+	// If Bootstrap is set, the program starts with bootstrap code that sets SP to 256 and calls
+	// Sys.init. Otherwise, it starts with the first command of the first file, and the translator
+	// adds an infinite loop at the end.
+	Bootstrap bool
+}
+
+// RunFiles translates several Hack VM files into one Hack assembly program and writes it to w.
+func RunFiles(files []File, w io.Writer, options Options) error {
+	names := make(map[string]bool)
+	for _, f := range files {
+		if names[f.Name] {
+			return fmt.Errorf("more than one file named %s.vm", f.Name)
+		}
+		names[f.Name] = true
+	}
+
+	// Parse and check all files first, so we can report all errors at once.
+	var errs ErrorList
+	programs := make([][]sourceCommand, len(files))
+	for i, f := range files {
+		parser := NewParser(f.Reader)
+		for parser.Parse() {
+			pos := Pos{f.Name + ".vm", parser.Line()}
+			programs[i] = append(programs[i], sourceCommand{parser.Command(), pos})
+		}
+		if err := parser.Err(); err != nil {
+			errs = append(errs, &Error{Pos{f.Name + ".vm", parser.Line()}, err.Error()})
+			continue
+		}
+		errs = append(errs, validate(programs[i])...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if options.Bootstrap {
+		NewTranslator(NewInstructionWriter(w, "$bootstrap"), "$bootstrap").bootstrap()
+	}
+	var t *Translator
+	for i, f := range files {
+		t = NewTranslator(NewInstructionWriter(w, f.Name), f.Name)
+		for _, c := range programs[i] {
+			if err := t.translate(c.Command); err != nil {
+				return &Error{c.Pos, err.Error()}
+			}
 		}
 	}
-	if err := parser.Err(); err != nil {
-		return err
+	if !options.Bootstrap && t != nil {
+		t.infiniteLoop()
 	}
-	t.infiniteLoop()
 	return nil
 }
 
@@ -74,6 +124,12 @@ func (t *Translator) translate(c Command) error {
 		t.translateFunction(c.Arg1, nVars)
 	case ReturnCommand:
 		t.translateReturn()
+	case CallCommand:
+		nArgs, err := strconv.Atoi(c.Arg2)
+		if err != nil {
+			return fmt.Errorf("expected decimal number: %s", c.Arg2)
+		}
+		t.translateCall(c.Arg1, nArgs)
 	default:
 		return fmt.Errorf("unexpected command type: %v", c.Type)
 	}
@@ -87,7 +143,7 @@ func (t *Translator) translatePush(segment string, index int) error {
 		t.WriteADecimal(index)
 		t.WriteC("D=A")
 	case "static":
-		t.WriteASymbolic(fmt.Sprintf("%s.%d", t.filename, index))
+		t.WriteASymbolic(t.staticSymbol(index))
 		t.WriteC("D=M")
 	case "temp", "pointer":
 		t.WriteADecimal(segmentAddresses[segment] + index)
@@ -107,6 +163,11 @@ func (t *Translator) translatePush(segment string, index int) error {
 }
 
 func (t *Translator) translatePop(segment string, index int) error {
+	switch segment {
+	case "static", "temp", "pointer", "local", "argument", "this", "that":
+	default:
+		return fmt.Errorf("invalid segment name for pop: %q", segment)
+	}
 	t.WriteComment("pop %s %d", segment, index)
 	switch segment {
 	case "local", "argument", "this", "that":
@@ -121,7 +182,7 @@ func (t *Translator) translatePop(segment string, index int) error {
 	t.pop()
 	switch segment {
 	case "static":
-		t.WriteASymbolic(fmt.Sprintf("%s.%d", index))
+		t.WriteASymbolic(t.staticSymbol(index))
 		t.WriteC("M=D")
 	case "temp", "pointer":
 		t.WriteADecimal(segmentAddresses[segment] + index)
@@ -134,6 +195,13 @@ func (t *Translator) translatePop(segment string, index int) error {
 	return nil
 }
 
+// staticSymbol returns the symbol for a static variable. Static variables belong to a file, so
+// they're named after the file, not the function: "static 3" in Ball.vm is Ball.3. The assembler
+// allocates a RAM address for each symbol.
+func (t *Translator) staticSymbol(index int) string {
+	return fmt.Sprintf("%s.%d", t.filename, index)
+}
+
 var segmentNames = map[string]string{
 	"local":    "LCL",
 	"argument": "ARG",
@@ -234,6 +302,7 @@ func (t *Translator) translateIf(label string) {
 
 func (t *Translator) translateFunction(functionName string, nVars int) {
 	t.WriteComment("function %s %d", functionName, nVars)
+	t.currentFunction = functionName
 	t.WriteLabel(functionName)
 	for i := 0; i < nVars; i++ {
 		t.WriteADecimal(0)
@@ -242,6 +311,45 @@ func (t *Translator) translateFunction(functionName string, nVars int) {
 	}
 }
 
+func (t *Translator) translateCall(functionName string, nArgs int) {
+	t.WriteBlank()
+	t.WriteComment("call %s %d", functionName, nArgs)
+	returnAddress := t.NewLabel()
+
+	t.WriteComment("(push return address)")
+	t.WriteASymbolic(returnAddress)
+	t.WriteC("D=A")
+	t.push()
+
+	for _, register := range strings.Split("LCL ARG THIS THAT", " ") {
+		t.WriteComment("(push %s)", register)
+		t.WriteASymbolic(register)
+		t.WriteC("D=M")
+		t.push()
+	}
+
+	t.WriteComment("(ARG = SP - 5 - %d)", nArgs)
+	t.WriteASymbolic("SP")
+	t.WriteC("D=M")
+	t.WriteADecimal(5 + nArgs)
+	t.WriteC("D=D-A")
+	t.WriteASymbolic("ARG")
+	t.WriteC("M=D")
+
+	t.WriteComment("(LCL = SP)")
+	t.WriteASymbolic("SP")
+	t.WriteC("D=M")
+	t.WriteASymbolic("LCL")
+	t.WriteC("M=D")
+
+	t.WriteComment("(goto %s)", functionName)
+	t.WriteASymbolic(functionName)
+	t.WriteC("0;JMP")
+	t.WriteLabel(returnAddress)
+
+	t.WriteBlank()
+}
+
 func (t *Translator) translateReturn() {
 	t.WriteBlank()
 	t.WriteComment("return")
@@ -268,7 +376,6 @@ func (t *Translator) translateReturn() {
 	t.WriteComment("(SP = ARG + 1)")
 	t.WriteC("D=A+1")
 	t.WriteASymbolic("SP")
-	t.WriteC("A=M")
 	t.WriteC("M=D")
 
 	for _, register := range strings.Split("THAT THIS ARG LCL", " ") {
@@ -280,7 +387,6 @@ func (t *Translator) translateReturn() {
 		t.WriteC("A=D")
 		t.WriteC("D=M")
 		t.WriteASymbolic(register)
-		t.WriteC("A=M")
 		t.WriteC("M=D")
 	}
 
@@ -291,8 +397,25 @@ func (t *Translator) translateReturn() {
 	t.WriteBlank()
 }
 
+// buildLabel returns the assembly label for a VM label. Labels are scoped to the function they're
+// declared in, as "function$label"; for code outside any function, the file name takes the
+// function's place.
 func (t *Translator) buildLabel(label string) string {
-	return fmt.Sprintf("%s.%s$%s", t.filename, t.currentFunction, label)
+	scope := t.currentFunction
+	if scope == "" {
+		scope = t.filename
+	}
+	return fmt.Sprintf("%s$%s", scope, label)
+}
+
+// bootstrap writes the code that starts a VM program: it sets up the stack and calls Sys.init.
+func (t *Translator) bootstrap() {
+	t.WriteComment("bootstrap")
+	t.WriteADecimal(256)
+	t.WriteC("D=A")
+	t.WriteASymbolic("SP")
+	t.WriteC("M=D")
+	t.translateCall("Sys.init", 0)
 }
 
 func (t *Translator) infiniteLoop() {
@@ -204,7 +204,8 @@ func TestTranslate(t *testing.T) {
 		want = strings.ReplaceAll(want, "\t", "")
 		want = want + "\n"
 		var output strings.Builder
-		err := translate("filename", "f", c.command, NewInstructionWriter(&output, "filename"))
+		translator := NewTranslator(NewInstructionWriter(&output, "filename"), "filename")
+		err := translator.translate(c.command)
 		if err != nil {
 			t.Errorf("translate for\n%#v\nreturned error: %v", c.command, err)
 			continue
@@ -215,3 +216,316 @@ func TestTranslate(t *testing.T) {
 		}
 	}
 }
+
+// runVM translates VM code, runs it with the stack at 256, and returns the emulator.
+func runVM(t *testing.T, vmCode string) *emulator {
+	t.Helper()
+	var output strings.Builder
+	if err := Run("Test", strings.NewReader(vmCode), &output); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	e := newEmulator(t, output.String())
+	e.RAM[0] = 256
+	e.run(t, 100000)
+	return e
+}
+
+func TestCall(t *testing.T) {
+	vmCode := `
+push constant 3
+push constant 4
+call Test.add 2
+pop temp 0
+label END
+goto END
+
+// add returns the sum of its two arguments, using a local variable and another call
+function Test.add 1
+push argument 0
+push argument 1
+add
+pop local 0
+push local 0
+call Test.double 1
+return
+
+function Test.double 0
+push argument 0
+push argument 0
+add
+return
+`
+	e := runVM(t, vmCode)
+	if got := e.RAM[5]; got != 14 {
+		t.Errorf("result is %d, want 14", got)
+	}
+	if got := e.RAM[0]; got != 256 {
+		t.Errorf("SP is %d after the call, want 256", got)
+	}
+}
+
+func TestCallSavesFrame(t *testing.T) {
+	vmCode := `
+push constant 10
+push constant 20
+call Test.f 2
+label END
+goto END
+
+function Test.f 2
+push constant 3000
+pop pointer 0
+push constant 4000
+pop pointer 1
+push constant 0
+return
+`
+	var output strings.Builder
+	if err := Run("Test", strings.NewReader(vmCode), &output); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	e := newEmulator(t, output.String())
+	e.RAM[0], e.RAM[1], e.RAM[2], e.RAM[3], e.RAM[4] = 256, 300, 400, 3030, 3040
+	e.run(t, 100000)
+	want := []uint16{257, 300, 400, 3030, 3040}
+	for i, w := range want {
+		if got := e.RAM[i]; got != w {
+			t.Errorf("RAM[%d] is %d after the call, want %d", i, got, w)
+		}
+	}
+	if got := e.RAM[256]; got != 0 {
+		t.Errorf("return value is %d, want 0", got)
+	}
+}
+
+func TestCallRecursive(t *testing.T) {
+	vmCode := `
+push constant 10
+call Test.fib 1
+pop temp 0
+label END
+goto END
+
+// fib returns the nth Fibonacci number
+function Test.fib 0
+push argument 0
+push constant 2
+lt
+if-goto BASE
+push argument 0
+push constant 1
+sub
+call Test.fib 1
+push argument 0
+push constant 2
+sub
+call Test.fib 1
+add
+return
+label BASE
+push argument 0
+return
+`
+	e := runVM(t, vmCode)
+	if got := e.RAM[5]; got != 55 {
+		t.Errorf("fib(10) is %d, want 55", got)
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	mainVM := `
+function Sys.init 0
+push constant 6
+call Math.square 1
+pop temp 0
+label END
+goto END
+`
+	mathVM := `
+function Math.square 0
+push argument 0
+push argument 0
+call Math.multiply 2
+return
+
+function Math.multiply 1
+label LOOP
+push argument 1
+push constant 0
+eq
+if-goto DONE
+push local 0
+push argument 0
+add
+pop local 0
+push argument 1
+push constant 1
+sub
+pop argument 1
+goto LOOP
+label DONE
+push local 0
+return
+`
+	files := []File{
+		{"Sys", strings.NewReader(mainVM)},
+		{"Math", strings.NewReader(mathVM)},
+	}
+	var output strings.Builder
+	if err := RunFiles(files, &output, Options{Bootstrap: true}); err != nil {
+		t.Fatalf("RunFiles returned error: %v", err)
+	}
+	code := output.String()
+	if !strings.HasPrefix(code, "// bootstrap\n@256\nD=A\n@SP\nM=D\n") {
+		t.Errorf("RunFiles produced code that doesn't start with the bootstrap code:\n%s", code)
+	}
+	if strings.Contains(code, "infinite loop") {
+		t.Errorf("RunFiles with bootstrap code added an infinite loop")
+	}
+
+	// the emulator starts with SP at 0, so the bootstrap code has to set it
+	e := newEmulator(t, code)
+	e.run(t, 100000)
+	if got := e.RAM[5]; got != 36 {
+		t.Errorf("result is %d, want 36", got)
+	}
+}
+
+func TestRunFilesDuplicateName(t *testing.T) {
+	files := []File{
+		{"Main", strings.NewReader("push constant 1\n")},
+		{"Main", strings.NewReader("push constant 2\n")},
+	}
+	var output strings.Builder
+	if err := RunFiles(files, &output, Options{}); err == nil {
+		t.Errorf("RunFiles with two files named Main did not return error")
+	}
+}
+
+func TestStatic(t *testing.T) {
+	// Both files use static 0 and static 1. Within a file, all functions share them.
+	sysVM := `
+function Sys.init 0
+push constant 10
+pop static 0
+push constant 11
+pop static 1
+call Counter.increment 0
+pop temp 0
+call Counter.increment 0
+pop temp 0
+call Sys.get 0
+pop temp 0
+call Counter.get 0
+pop temp 1
+push static 1
+pop temp 2
+label END
+goto END
+
+function Sys.get 0
+push static 0
+return
+`
+	counterVM := `
+function Counter.increment 0
+push static 0
+push constant 1
+add
+pop static 0
+push constant 20
+pop static 1
+push constant 0
+return
+
+function Counter.get 0
+push static 0
+return
+`
+	files := []File{
+		{"Sys", strings.NewReader(sysVM)},
+		{"Counter", strings.NewReader(counterVM)},
+	}
+	var output strings.Builder
+	if err := RunFiles(files, &output, Options{Bootstrap: true}); err != nil {
+		t.Fatalf("RunFiles returned error: %v", err)
+	}
+	code := output.String()
+	for _, symbol := range []string{"@Sys.0", "@Sys.1", "@Counter.0", "@Counter.1"} {
+		if !strings.Contains(code, symbol+"\n") {
+			t.Errorf("RunFiles produced code without %s", symbol)
+		}
+	}
+
+	e := newEmulator(t, code)
+	e.run(t, 100000)
+	want := []uint16{10, 2, 11}
+	for i, w := range want {
+		if got := e.RAM[5+i]; got != w {
+			t.Errorf("temp %d is %d, want %d", i, got, w)
+		}
+	}
+}
+
+func TestLabelScope(t *testing.T) {
+	// The code outside functions and both functions have a label LOOP. Test.f counts down from 3
+	// and calls Test.g, which counts down from its argument and counts its iterations in temp 2.
+	vmCode := `
+push constant 3
+call Test.f 1
+pop temp 0
+label LOOP
+goto LOOP
+
+function Test.f 0
+label LOOP
+push argument 0
+push constant 1
+sub
+pop argument 0
+push argument 0
+call Test.g 1
+pop temp 1
+push argument 0
+if-goto LOOP
+push constant 100
+return
+
+function Test.g 0
+label LOOP
+push argument 0
+if-goto BODY
+push constant 0
+return
+label BODY
+push temp 2
+push constant 1
+add
+pop temp 2
+push argument 0
+push constant 1
+sub
+pop argument 0
+goto LOOP
+`
+	var output strings.Builder
+	if err := Run("Test", strings.NewReader(vmCode), &output); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	code := output.String()
+	for _, label := range []string{"(Test$LOOP)", "(Test.f$LOOP)", "(Test.g$LOOP)", "(Test.g$BODY)"} {
+		if !strings.Contains(code, label+"\n") {
+			t.Errorf("Run produced code without %s", label)
+		}
+	}
+
+	e := newEmulator(t, code)
+	e.RAM[0] = 256
+	e.run(t, 100000)
+	if got := e.RAM[5]; got != 100 {
+		t.Errorf("result is %d, want 100", got)
+	}
+	if got := e.RAM[7]; got != 3 {
+		t.Errorf("Test.g went through its loop %d times, want 3", got)
+	}
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A Pos is the position of a command in a VM file.
+type Pos struct {
+	File string
+	Line int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// An Error is an error in a VM program, with the position where it occurred.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s", e.Pos, e.Msg)
+}
+
+// An ErrorList is a list of errors found in a VM program.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, e := range l {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Err returns the list as an error, or nil if it's empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// A sourceCommand is a command along with its position in the source.
+type sourceCommand struct {
+	Command
+	Pos Pos
+}
+
+// maxConstant is the largest number an A-instruction can load.
+const maxConstant = 32767
+
+// segmentSizes gives the number of entries in segments that have a fixed size.
+var segmentSizes = map[string]int{
+	"temp":    8,
+	"pointer": 2,
+}
+
+// validate checks the commands of one VM file for errors the parser doesn't catch: segments that
+// don't exist or can't be used with pop, indexes out of range, invalid numbers in function and call
+// commands, labels declared twice in a function, and jumps to labels that aren't defined in the
+// same function.
+func validate(commands []sourceCommand) ErrorList {
+	var errs ErrorList
+	errorf := func(c sourceCommand, format string, a ...any) {
+		errs = append(errs, &Error{c.Pos, fmt.Sprintf(format, a...)})
+	}
+
+	// labels[function][label] is the line where the function declares the label; commands before
+	// the first function command belong to the function ""
+	labels := make(map[string]map[string]int)
+	definedIn := make(map[string]string) // function where a label is first defined
+	function := ""
+	functions := make([]string, len(commands))
+	for i, c := range commands {
+		switch c.Type {
+		case FunctionCommand:
+			function = c.Arg1
+		case LabelCommand:
+			if labels[function] == nil {
+				labels[function] = make(map[string]int)
+			}
+			if first, ok := labels[function][c.Arg1]; ok {
+				errorf(c, "label %q declared twice in %s (first on line %d)", c.Arg1, describe(function), first)
+			} else {
+				labels[function][c.Arg1] = c.Pos.Line
+			}
+			if _, ok := definedIn[c.Arg1]; !ok {
+				definedIn[c.Arg1] = function
+			}
+		}
+		functions[i] = function
+	}
+
+	for i, c := range commands {
+		switch c.Type {
+		case PushCommand, PopCommand:
+			segment := c.Arg1
+			index, err := strconv.Atoi(c.Arg2)
+			switch {
+			case segment == "constant" && c.Type == PopCommand:
+				errorf(c, "can't pop to the constant segment")
+			case segment != "constant" && segment != "static" && segmentNames[segment] == "" &&
+				segmentSizes[segment] == 0:
+				errorf(c, "invalid segment name: %q", segment)
+			case err != nil || index < 0:
+				errorf(c, "invalid index: %q", c.Arg2)
+			case segmentSizes[segment] > 0 && index >= segmentSizes[segment]:
+				errorf(c, "index out of range for %s segment: %d (maximum is %d)", segment, index, segmentSizes[segment]-1)
+			case index > maxConstant:
+				errorf(c, "index out of range for %s segment: %d (maximum is %d)", segment, index, maxConstant)
+			}
+		case FunctionCommand, CallCommand:
+			n, err := strconv.Atoi(c.Arg2)
+			name := "local variables"
+			if c.Type == CallCommand {
+				name = "arguments"
+			}
+			if err != nil || n < 0 || n > maxConstant {
+				errorf(c, "invalid number of %s: %q", name, c.Arg2)
+			}
+		case GotoCommand, IfCommand:
+			label := c.Arg1
+			if _, ok := labels[functions[i]][label]; ok {
+				continue
+			}
+			if f, ok := definedIn[label]; ok {
+				errorf(c, "label %q is defined in %s, not in %s", label, describe(f), describe(functions[i]))
+			} else {
+				errorf(c, "undefined label: %q", label)
+			}
+		}
+	}
+	slices.SortStableFunc(errs, func(a, b *Error) int {
+		return cmp.Compare(a.Pos.Line, b.Pos.Line)
+	})
+	return errs
+}
+
+// describe returns a description of a function for error messages.
+func describe(function string) string {
+	if function == "" {
+		return "the code outside functions"
+	}
+	return "function " + function
+}
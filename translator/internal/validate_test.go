@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		vmCode string
+		want   []string // errors
+	}{
+		{"push constant 32767\npush temp 7\npop pointer 1\npush static 200\n", nil},
+		{"pop constant 5\n", []string{"Test.vm:1: can't pop to the constant segment"}},
+		{"push stack 1\npop heap 0\n", []string{
+			`Test.vm:1: invalid segment name: "stack"`,
+			`Test.vm:2: invalid segment name: "heap"`,
+		}},
+		{"push temp 8\npop pointer 2\npush constant 40000\npush local -1\n", []string{
+			"Test.vm:1: index out of range for temp segment: 8 (maximum is 7)",
+			"Test.vm:2: index out of range for pointer segment: 2 (maximum is 1)",
+			"Test.vm:3: index out of range for constant segment: 40000 (maximum is 32767)",
+			`Test.vm:4: invalid index: "-1"`,
+		}},
+		{"function Test.f x\ncall Test.f -2\n", []string{
+			`Test.vm:1: invalid number of local variables: "x"`,
+			`Test.vm:2: invalid number of arguments: "-2"`,
+		}},
+		{
+			"function Test.f 0\nlabel LOOP\ngoto LOOP\n\nfunction Test.g 0\n// comment\nif-goto LOOP\ngoto END\n",
+			[]string{
+				`Test.vm:7: label "LOOP" is defined in function Test.f, not in function Test.g`,
+				`Test.vm:8: undefined label: "END"`,
+			},
+		},
+		{
+			"label START\nfunction Test.f 0\nlabel LOOP\nlabel START\nlabel LOOP\nfunction Test.g 0\nlabel LOOP\n",
+			[]string{`Test.vm:5: label "LOOP" declared twice in function Test.f (first on line 3)`},
+		},
+	}
+	for _, c := range cases {
+		var output strings.Builder
+		err := Run("Test", strings.NewReader(c.vmCode), &output)
+		var got []string
+		if err != nil {
+			got = strings.Split(err.Error(), "\n")
+		}
+		if strings.Join(got, "\n") != strings.Join(c.want, "\n") {
+			t.Errorf("Run(%q) returned errors:\n%s\nwant:\n%s", c.vmCode, err, strings.Join(c.want, "\n"))
+		}
+	}
+}
+
+func TestRunFilesErrorPositions(t *testing.T) {
+	files := []File{
+		{"Main", strings.NewReader("push constant 1\npop constant 1\n")},
+		{"Other", strings.NewReader("// comment\n\nfoo bar\n")},
+	}
+	var output strings.Builder
+	err := RunFiles(files, &output, Options{})
+	want := "Main.vm:2: can't pop to the constant segment\nOther.vm:3: invalid VM command: \"foo bar\""
+	if err == nil || err.Error() != want {
+		t.Errorf("RunFiles returned error:\n%v\nwant:\n%s", err, want)
+	}
+	if output.Len() > 0 {
+		t.Errorf("RunFiles wrote output for an invalid program:\n%s", output.String())
+	}
+}
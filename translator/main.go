@@ -3,51 +3,130 @@ The translator translates Hack VM programs (.vm files) into Hack assembly progra
 
 Usage:
 
-	translator program.vm
+	translator [flags] program.vm
+	translator [flags] directory
+	translator [flags] file.vm more.vm ...
 
-This will read program.vm and write assembly code to program.asm.
+With a single file, this will read program.vm and write assembly code to program.asm. With a
+directory, it translates all .vm files in the directory into one program, named after the directory
+and written to it: translating Pong writes Pong/Pong.asm. With several files, they're combined into
+one program named after the first file.
+
+Static variables belong to the file that declares them, so "static 0" in Ball.vm and in Bat.vm are
+different variables.
+
+Flags:
+
+	-bootstrap  start the program with bootstrap code that sets SP to 256 and calls Sys.init; on by
+	            default for a directory or more than one file, -bootstrap=false turns it off
+	-o file     write the assembly code to file instead
 */
 package main
 
 import (
 	"github.com/lfritz/nand2tetris/translator/internal"
 
+	"flag"
 	"fmt"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
 )
 
 func main() {
-	// check command-line argument
-	args := os.Args[1:]
-	if len(args) != 1 {
-		errorAndExit("Usage: translator input.vm")
+	// check command-line arguments
+	bootstrap := flag.Bool("bootstrap", false, "write bootstrap code")
+	outPath := flag.String("o", "", "output file")
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
 	}
 
 	// figure out input and output file names
-	inPath := args[0]
-	filename, ok := strings.CutSuffix(inPath, ".vm")
-	if !ok {
-		errorAndExit("error: input filename must end in .vm")
+	var inPaths []string
+	directory := false
+	firstIsDir := false
+	for i, arg := range args {
+		info, err := os.Stat(arg)
+		check(err)
+		if i == 0 {
+			firstIsDir = info.IsDir()
+		}
+		if !info.IsDir() {
+			if !strings.HasSuffix(arg, ".vm") {
+				errorAndExit("error: input filename must end in .vm: %s", arg)
+			}
+			inPaths = append(inPaths, arg)
+			continue
+		}
+		directory = true
+		paths, err := filepath.Glob(filepath.Join(arg, "*.vm"))
+		check(err)
+		if len(paths) == 0 {
+			errorAndExit("error: no .vm files in %s", arg)
+		}
+		inPaths = append(inPaths, paths...)
+	}
+	if *outPath == "" {
+		var err error
+		*outPath, err = outputPath(args[0], firstIsDir, len(args) == 1)
+		check(err)
+	}
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		explicit = explicit || f.Name == "bootstrap"
+	})
+	if !explicit {
+		*bootstrap = directory || len(inPaths) > 1
 	}
-	outPath := filename + ".asm"
 
-	// open input file
-	inFile, err := os.Open(inPath)
-	check(err)
-	defer inFile.Close()
+	// open input files
+	var files []internal.File
+	for _, inPath := range inPaths {
+		inFile, err := os.Open(inPath)
+		check(err)
+		defer inFile.Close()
+		name := strings.TrimSuffix(filepath.Base(inPath), ".vm")
+		files = append(files, internal.File{Name: name, Reader: inFile})
+	}
 
 	// open output file
-	outFile, err := os.Create(outPath)
+	outFile, err := os.Create(*outPath)
 	check(err)
 	defer outFile.Close()
 
 	// run the translator
-	err = internal.Run(path.Base(filename), inFile, outFile)
+	err = internal.RunFiles(files, outFile, internal.Options{Bootstrap: *bootstrap})
+	if err != nil {
+		// don't leave a partial output file behind
+		outFile.Close()
+		os.Remove(*outPath)
+	}
 	check(err)
 }
 
+// outputPath returns the default output file given the first argument: program.asm for
+// program.vm, and for a directory, a file named after the directory. If the directory is the only
+// argument, the file goes into it; otherwise, next to it.
+func outputPath(arg string, isDir, only bool) (string, error) {
+	if !isDir {
+		return strings.TrimSuffix(arg, ".vm") + ".asm", nil
+	}
+	// the name of a path like "." or "../Pong/.." only shows in the absolute path
+	abs, err := filepath.Abs(arg)
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Base(abs) + ".asm"
+	if only {
+		return filepath.Join(arg, name), nil
+	}
+	return filepath.Join(arg, "..", name), nil
+}
+
 func check(err error) {
 	if err == nil {
 		return
@@ -60,3 +139,13 @@ func errorAndExit(format string, a ...any) {
 	fmt.Fprintln(os.Stderr)
 	os.Exit(1)
 }
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "    translator [flags] program.vm")
+	fmt.Fprintln(os.Stderr, "    translator [flags] directory")
+	fmt.Fprintln(os.Stderr, "    translator [flags] file.vm more.vm ...")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fmt.Fprintln(os.Stderr, "    -bootstrap  write bootstrap code (default for a directory or several files)")
+	fmt.Fprintln(os.Stderr, "    -o file     write the assembly code to file")
+}
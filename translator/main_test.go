@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputPath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	here := filepath.Base(wd)
+	cases := []struct {
+		arg         string
+		isDir, only bool
+		want        string
+	}{
+		{"prog.vm", false, true, "prog.asm"},
+		{"dir/prog.vm", false, false, "dir/prog.asm"},
+		{"Pong", true, true, "Pong/Pong.asm"},
+		{"games/Pong/", true, true, "games/Pong/Pong.asm"},
+		{".", true, true, here + ".asm"},
+		{".", true, false, "../" + here + ".asm"},
+		{"Pong", true, false, "Pong.asm"},
+	}
+	for _, c := range cases {
+		got, err := outputPath(c.arg, c.isDir, c.only)
+		if err != nil {
+			t.Errorf("outputPath(%q, %v, %v) returned error: %v", c.arg, c.isDir, c.only, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("outputPath(%q, %v, %v) returned %q, want %q", c.arg, c.isDir, c.only, got, c.want)
+		}
+	}
+}